@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrWrongPassword is returned by CheckPassword when the password does not
+// match the hash.
+var ErrWrongPassword = errors.New("wrong password")
+
+// NewToken returns a random token and the hash to store for it. Only the hash
+// is kept in the database; the token itself is shown to the user once.
+func NewToken() (string, string, error) {
+	buf := make([]byte, 32)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(buf)
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the stored form of a token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// FeverKey returns the Fever API key for a user name and password, which
+// Fever clients send as md5("name:password").
+func FeverKey(name, password string) string {
+	sum := md5.Sum([]byte(name + ":" + password))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashPassword returns the bcrypt hash to store for a password
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches a hash from HashPassword
+func CheckPassword(hash, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrWrongPassword
+	}
+	return err
+}
@@ -0,0 +1,315 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theMagicRabbit/gator/internal/store"
+)
+
+// Backup is a copy of every table gator keeps, written as JSON before data
+// is deleted. Sessions are left out as they only log users in.
+type Backup struct {
+	CreatedAt		time.Time		`json:"created_at"`
+	Users			[]User			`json:"users"`
+	UserPasswords	[]UserPassword	`json:"user_passwords"`
+	Feeds			[]Feed			`json:"feeds"`
+	FeedFollows		[]FeedFollow	`json:"feed_follows"`
+	Posts			[]Post			`json:"posts"`
+	PostFeeds		[]PostFeed		`json:"post_feeds"`
+	PostReads		[]PostRead		`json:"post_reads"`
+	PostStars		[]PostStar		`json:"post_stars"`
+	APITokens		[]APIToken		`json:"api_tokens"`
+	PublishTokens	[]PublishToken	`json:"publish_tokens"`
+}
+
+type User struct {
+	ID			uuid.UUID	`json:"id"`
+	CreatedAt	time.Time	`json:"created_at"`
+	UpdatedAt	time.Time	`json:"updated_at"`
+	Name		string		`json:"name"`
+}
+
+type UserPassword struct {
+	UserID			uuid.UUID	`json:"user_id"`
+	UpdatedAt		time.Time	`json:"updated_at"`
+	PasswordHash	string		`json:"password_hash"`
+}
+
+type Feed struct {
+	ID					uuid.UUID	`json:"id"`
+	SerialID			int64		`json:"serial_id"`
+	CreatedAt			time.Time	`json:"created_at"`
+	UpdatedAt			time.Time	`json:"updated_at"`
+	Name				string		`json:"name"`
+	URL					string		`json:"url"`
+	UserID				uuid.UUID	`json:"user_id"`
+	LastFetchedAt		*time.Time	`json:"last_fetched_at"`
+	ETag				*string		`json:"etag"`
+	LastModified		*string		`json:"last_modified"`
+	ConsecutiveFailures	int32		`json:"consecutive_failures"`
+	LastError			*string		`json:"last_error"`
+	NextFetchAt			*time.Time	`json:"next_fetch_at"`
+}
+
+type FeedFollow struct {
+	ID			uuid.UUID	`json:"id"`
+	CreatedAt	time.Time	`json:"created_at"`
+	UpdatedAt	time.Time	`json:"updated_at"`
+	UserID		uuid.UUID	`json:"user_id"`
+	FeedID		uuid.UUID	`json:"feed_id"`
+	Category	*string		`json:"category"`
+}
+
+type Post struct {
+	ID			uuid.UUID	`json:"id"`
+	SerialID	int64		`json:"serial_id"`
+	CreatedAt	time.Time	`json:"created_at"`
+	UpdatedAt	time.Time	`json:"updated_at"`
+	Title		*string		`json:"title"`
+	Description	*string		`json:"description"`
+	URL			*string		`json:"url"`
+	PublishedAt	*time.Time	`json:"published_at"`
+	Author		*string		`json:"author"`
+	Categories	[]string	`json:"categories"`
+}
+
+type PostFeed struct {
+	PostID		uuid.UUID	`json:"post_id"`
+	FeedID		uuid.UUID	`json:"feed_id"`
+	GUID		string		`json:"guid"`
+	CreatedAt	time.Time	`json:"created_at"`
+}
+
+type PostRead struct {
+	UserID	uuid.UUID	`json:"user_id"`
+	PostID	uuid.UUID	`json:"post_id"`
+	ReadAt	time.Time	`json:"read_at"`
+}
+
+type PostStar struct {
+	UserID		uuid.UUID	`json:"user_id"`
+	PostID		uuid.UUID	`json:"post_id"`
+	StarredAt	time.Time	`json:"starred_at"`
+}
+
+type APIToken struct {
+	ID			uuid.UUID	`json:"id"`
+	CreatedAt	time.Time	`json:"created_at"`
+	UserID		uuid.UUID	`json:"user_id"`
+	Name		string		`json:"name"`
+	TokenHash	string		`json:"token_hash"`
+	FeverKey	*string		`json:"fever_key"`
+}
+
+type PublishToken struct {
+	UserID		uuid.UUID	`json:"user_id"`
+	CreatedAt	time.Time	`json:"created_at"`
+	TokenHash	string		`json:"token_hash"`
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func nullString(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+// Take reads every table from db. Run it in the same transaction as the
+// deletion it guards so the backup holds exactly what is deleted.
+func Take(ctx context.Context, db store.Store) (*Backup, error) {
+	b := &Backup{CreatedAt: time.Now().UTC()}
+	users, err := db.BackupUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b.Users = make([]User, 0, len(users))
+	for _, u := range users {
+		b.Users = append(b.Users, User{
+			ID: u.ID,
+			CreatedAt: u.CreatedAt,
+			UpdatedAt: u.UpdatedAt,
+			Name: u.Name,
+		})
+	}
+	passwords, err := db.BackupUserPasswords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b.UserPasswords = make([]UserPassword, 0, len(passwords))
+	for _, p := range passwords {
+		b.UserPasswords = append(b.UserPasswords, UserPassword{
+			UserID: p.UserID,
+			UpdatedAt: p.UpdatedAt,
+			PasswordHash: p.PasswordHash,
+		})
+	}
+	feeds, err := db.BackupFeeds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b.Feeds = make([]Feed, 0, len(feeds))
+	for _, f := range feeds {
+		b.Feeds = append(b.Feeds, Feed{
+			ID: f.ID,
+			SerialID: f.SerialID,
+			CreatedAt: f.CreatedAt,
+			UpdatedAt: f.UpdatedAt,
+			Name: f.Name,
+			URL: f.Url,
+			UserID: f.UserID,
+			LastFetchedAt: nullTime(f.LastFetchedAt),
+			ETag: nullString(f.Etag),
+			LastModified: nullString(f.LastModified),
+			ConsecutiveFailures: f.ConsecutiveFailures,
+			LastError: nullString(f.LastError),
+			NextFetchAt: nullTime(f.NextFetchAt),
+		})
+	}
+	follows, err := db.BackupFeedFollows(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b.FeedFollows = make([]FeedFollow, 0, len(follows))
+	for _, ff := range follows {
+		b.FeedFollows = append(b.FeedFollows, FeedFollow{
+			ID: ff.ID,
+			CreatedAt: ff.CreatedAt,
+			UpdatedAt: ff.UpdatedAt,
+			UserID: ff.UserID,
+			FeedID: ff.FeedID,
+			Category: nullString(ff.Category),
+		})
+	}
+	posts, err := db.BackupPosts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b.Posts = make([]Post, 0, len(posts))
+	for _, p := range posts {
+		b.Posts = append(b.Posts, Post{
+			ID: p.ID,
+			SerialID: p.SerialID,
+			CreatedAt: p.CreatedAt,
+			UpdatedAt: p.UpdatedAt,
+			Title: nullString(p.Title),
+			Description: nullString(p.Description),
+			URL: nullString(p.Url),
+			PublishedAt: nullTime(p.PublishedAt),
+			Author: nullString(p.Author),
+			Categories: p.Categories,
+		})
+	}
+	postFeeds, err := db.BackupPostFeeds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b.PostFeeds = make([]PostFeed, 0, len(postFeeds))
+	for _, pf := range postFeeds {
+		b.PostFeeds = append(b.PostFeeds, PostFeed{
+			PostID: pf.PostID,
+			FeedID: pf.FeedID,
+			GUID: pf.Guid,
+			CreatedAt: pf.CreatedAt,
+		})
+	}
+	reads, err := db.BackupPostReads(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b.PostReads = make([]PostRead, 0, len(reads))
+	for _, r := range reads {
+		b.PostReads = append(b.PostReads, PostRead{
+			UserID: r.UserID,
+			PostID: r.PostID,
+			ReadAt: r.ReadAt,
+		})
+	}
+	stars, err := db.BackupPostStars(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b.PostStars = make([]PostStar, 0, len(stars))
+	for _, s := range stars {
+		b.PostStars = append(b.PostStars, PostStar{
+			UserID: s.UserID,
+			PostID: s.PostID,
+			StarredAt: s.StarredAt,
+		})
+	}
+	apiTokens, err := db.BackupAPITokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b.APITokens = make([]APIToken, 0, len(apiTokens))
+	for _, t := range apiTokens {
+		b.APITokens = append(b.APITokens, APIToken{
+			ID: t.ID,
+			CreatedAt: t.CreatedAt,
+			UserID: t.UserID,
+			Name: t.Name,
+			TokenHash: t.TokenHash,
+			FeverKey: nullString(t.FeverKey),
+		})
+	}
+	publishTokens, err := db.BackupPublishTokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b.PublishTokens = make([]PublishToken, 0, len(publishTokens))
+	for _, t := range publishTokens {
+		b.PublishTokens = append(b.PublishTokens, PublishToken{
+			UserID: t.UserID,
+			CreatedAt: t.CreatedAt,
+			TokenHash: t.TokenHash,
+		})
+	}
+	return b, nil
+}
+
+// Write writes the backup as indented JSON
+func (b *Backup) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(b)
+}
+
+// DefaultPath returns where a backup taken at t is written when no file is
+// given: a timestamped file in the user's home directory.
+func DefaultPath(t time.Time) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf(".gator-backup-%s.json", t.UTC().Format("20060102T150405.000Z"))
+	return filepath.Join(home, name), nil
+}
+
+// WriteFile writes the backup to a new file that only its owner can read,
+// as it holds password and token hashes. An existing file is not replaced.
+func (b *Backup) WriteFile(path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	err = b.Write(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
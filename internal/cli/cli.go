@@ -1,18 +1,34 @@
 package cli
 
 import (
+	"bufio"
 	"context"
 	"database/sql"
 	"errors"
+	"flag"
 	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/lib/pq"
+	"github.com/theMagicRabbit/gator/internal/auth"
+	"github.com/theMagicRabbit/gator/internal/backup"
 	"github.com/theMagicRabbit/gator/internal/database"
 	"github.com/theMagicRabbit/gator/internal/feed"
+	"github.com/theMagicRabbit/gator/internal/opml"
+	"github.com/theMagicRabbit/gator/internal/publish"
+	"github.com/theMagicRabbit/gator/internal/server"
 	"github.com/theMagicRabbit/gator/internal/state"
+	"github.com/theMagicRabbit/gator/internal/store"
+	"golang.org/x/term"
 )
 
 type Command struct {
@@ -61,18 +77,23 @@ func HandlerAddFeed(s *state.State, cmd Command, user database.User) error {
 		Url: cmd.Args[1],
 		UserID: user.ID,
 	}
-	createFeed, err := s.Db.CreateFeed(context.Background(), params)
-	if err != nil {
+	var following database.CreateFeedFollowsRow
+	// The feed is only kept if following it succeeds
+	err := s.Db.InTx(context.Background(), func(qtx store.Store) error {
+		createFeed, err := qtx.CreateFeed(context.Background(), params)
+		if err != nil {
+			return err
+		}
+		feedFollowParams := database.CreateFeedFollowsParams{
+			ID: uuid.New(),
+			CreatedAt: utcTime,
+			UpdatedAt: utcTime,
+			UserID: user.ID,
+			FeedID: createFeed.ID,
+		}
+		following, err = qtx.CreateFeedFollows(context.Background(), feedFollowParams)
 		return err
-	}
-	feedFollowParams := database.CreateFeedFollowsParams{
-		ID: uuid.New(),
-		CreatedAt: utcTime,
-		UpdatedAt: utcTime,
-		UserID: user.ID,
-		FeedID: createFeed.ID,
-	}
-	following, err := s.Db.CreateFeedFollows(context.Background(), feedFollowParams)
+	})
 	if err != nil {
 		return err
 	}
@@ -80,33 +101,82 @@ func HandlerAddFeed(s *state.State, cmd Command, user database.User) error {
 	return nil
 }
 
+// staleBefore returns the cutoff for feeds due in an agg run. Feeds fetched
+// within the last half interval are left for the next tick, which also keeps
+// other agg processes from refetching them.
+func staleBefore(interval time.Duration) time.Time {
+	return time.Now().UTC().Add(-interval / 2)
+}
+
 func HandlerAgg(s *state.State, cmd Command) error {
-	if argLen := len(cmd.Args); argLen < 1 {
+	flags := flag.NewFlagSet("agg", flag.ContinueOnError)
+	concurrency := flags.Int("concurrency", 4, "number of feeds to fetch in parallel")
+	once := flags.Bool("once", false, "fetch every due feed once, then exit")
+	err := flags.Parse(cmd.Args)
+	if err != nil {
+		return err
+	}
+	argLen := flags.NArg()
+	if argLen < 1 && !*once {
 		return fmt.Errorf("agg requires one argument; zero provided.")
 	} else if argLen > 1 {
 		return fmt.Errorf("agg requires one argument; %d provided.", argLen)
 	}
-	duration_between_reqs, err := time.ParseDuration(cmd.Args[0])
-	if err != nil {
+	if *concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1; %d provided.", *concurrency)
+	}
+	var duration_between_reqs time.Duration
+	if argLen == 1 {
+		duration_between_reqs, err = time.ParseDuration(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		// Restore default signal handling so a second signal exits immediately
+		stop()
+	}()
+
+	if *once {
+		summary, err := feed.ScrapeFeeds(ctx, s, *concurrency, staleBefore(duration_between_reqs))
+		fmt.Printf("Fetched %d feeds (%d not modified), %d failed, %d new posts\n", summary.Fetched, summary.NotModified, summary.Failed, summary.NewPosts)
 		return err
 	}
-	fmt.Printf("Collecting feeds every %s\n", duration_between_reqs.String())
+
+	fmt.Printf("Collecting feeds every %s with %d workers\n", duration_between_reqs.String(), *concurrency)
 	ticker := time.NewTicker(duration_between_reqs)
-	for ; ; <-ticker.C {
-		err := feed.ScrapeFeeds(s)
+	defer ticker.Stop()
+	for {
+		_, err := feed.ScrapeFeeds(ctx, s, *concurrency, staleBefore(duration_between_reqs))
 		if err != nil {
-			return err
+			// Keep aggregating; the next tick may find the database reachable again
+			fmt.Println(err)
+		}
+		select {
+		case <-ctx.Done():
+			fmt.Println("Shutting down")
+			return nil
+		case <-ticker.C:
 		}
 	}
 }
 
 func HandlerBrowse(s *state.State, cmd Command, user database.User) error {
+	flags := flag.NewFlagSet("browse", flag.ContinueOnError)
+	includeRead := flags.Bool("all", false, "include posts that have been read")
+	err := flags.Parse(cmd.Args)
+	if err != nil {
+		return err
+	}
 	var postLimit int
-	var err error
-	if argLen := len(cmd.Args); argLen > 1 {
+	if argLen := flags.NArg(); argLen > 1 {
 		return fmt.Errorf("browse has one optional argument; %d provided.", argLen)
 	} else if argLen == 1 {
-		postLimit, err = strconv.Atoi(cmd.Args[0])
+		postLimit, err = strconv.Atoi(flags.Arg(0))
 		if err != nil {
 			return err
 		}
@@ -115,15 +185,57 @@ func HandlerBrowse(s *state.State, cmd Command, user database.User) error {
 	}
 	params := database.GetPostsForUserParams{
 		UserID: user.ID,
-		Limit: int32(postLimit),
+		IncludeRead: *includeRead,
+		PostLimit: int32(postLimit),
 	}
 	posts, err := s.Db.GetPostsForUser(context.Background(), params)
 	if err != nil {
 		return err
 	}
 	for _, p := range posts {
-		fmt.Printf("%s: %s | %s\n", p.Title.String, p.Url.String, p.PublishedAt.Time.String())
+		readMarker := ""
+		if p.IsRead {
+			readMarker = " (read)"
+		}
+		fmt.Printf("[%s] %s: %s | %s%s\n", p.ID, p.Title.String, p.Url.String, p.PublishedAt.Time.String(), readMarker)
+	}
+	return nil
+}
+
+func HandlerExport(s *state.State, cmd Command, user database.User) error {
+	if argLen := len(cmd.Args); argLen > 1 {
+		return fmt.Errorf("export has one optional argument; %d provided.", argLen)
+	}
+	following, err := s.Db.GetFeedFollowsForUser(context.Background(), user.Name)
+	if err != nil {
+		return err
+	}
+	subs := make([]opml.Subscription, 0, len(following))
+	for _, f := range following {
+		subs = append(subs, opml.Subscription{
+			Name: f.Feedname,
+			URL: f.Feedurl,
+			Category: f.Category.String,
+		})
+	}
+	doc := opml.New(fmt.Sprintf("gator subscriptions for %s", user.Name), subs)
+	if len(cmd.Args) == 0 {
+		return doc.Write(os.Stdout)
 	}
+	file, err := os.Create(cmd.Args[0])
+	if err != nil {
+		return err
+	}
+	err = doc.Write(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d feeds to %s\n", len(subs), cmd.Args[0])
 	return nil
 }
 
@@ -138,6 +250,9 @@ func HandlerFeeds(s *state.State, cmd Command) error {
 			continue
 		}
 		fmt.Printf("[Feed %d]\nname: %s\nurl: %s\nusername: %s\n", i, f.Name, f.Url, username.Name)
+		if f.ConsecutiveFailures > 0 {
+			fmt.Printf("failures: %d\nlast error: %s\nnext attempt: %s\n", f.ConsecutiveFailures, f.LastError.String, f.NextFetchAt.Time.String())
+		}
 	}
 	return nil
 }
@@ -178,12 +293,95 @@ func HandlerFollowing(s *state.State, cmd Command, user database.User) error {
 		return err
 	}
 	for _, f := range following {
+		if f.Category.Valid {
+			fmt.Printf("User: %s\tSubscription: %s\tCategory: %s\n", f.Username, f.Feedname, f.Category.String)
+			continue
+		}
 		fmt.Printf("User: %s\tSubscription: %s\n", f.Username, f.Feedname)
 	}
 	
 	return nil
 }
 
+func HandlerImport(s *state.State, cmd Command, user database.User) error {
+	if argLen := len(cmd.Args); argLen < 1 {
+		return fmt.Errorf("import requires one argument; zero provided.")
+	} else if argLen > 1 {
+		return fmt.Errorf("import requires one argument; %d provided.", argLen)
+	}
+	file, err := os.Open(cmd.Args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	doc, err := opml.Parse(file)
+	if err != nil {
+		return err
+	}
+
+	var created, followed, skipped int
+	err = s.Db.InTx(context.Background(), func(qtx store.Store) error {
+		for _, sub := range doc.Subscriptions() {
+			utcTime := time.Now().UTC()
+			existingFeed, err := qtx.GetFeed(context.Background(), sub.URL)
+			if errors.Is(err, sql.ErrNoRows) {
+				name := sub.Name
+				if name == "" {
+					name = sub.URL
+				}
+				params := database.CreateFeedParams{
+					ID: uuid.New(),
+					CreatedAt: utcTime,
+					UpdatedAt: utcTime,
+					Name: name,
+					Url: sub.URL,
+					UserID: user.ID,
+				}
+				existingFeed, err = qtx.CreateFeed(context.Background(), params)
+				if err != nil {
+					return err
+				}
+				created++
+				fmt.Printf("created:  %s\n", sub.URL)
+			} else if err != nil {
+				return err
+			}
+			followParams := database.GetFeedFollowParams{
+				UserID: user.ID,
+				FeedID: existingFeed.ID,
+			}
+			_, err = qtx.GetFeedFollow(context.Background(), followParams)
+			if err == nil {
+				skipped++
+				fmt.Printf("skipped:  %s (already following)\n", sub.URL)
+				continue
+			} else if !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			params := database.CreateFeedFollowsParams{
+				ID: uuid.New(),
+				CreatedAt: utcTime,
+				UpdatedAt: utcTime,
+				UserID: user.ID,
+				FeedID: existingFeed.ID,
+				Category: sql.NullString{String: sub.Category, Valid: sub.Category != ""},
+			}
+			_, err = qtx.CreateFeedFollows(context.Background(), params)
+			if err != nil {
+				return err
+			}
+			followed++
+			fmt.Printf("followed: %s\n", sub.URL)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Import complete: %d feeds created, %d followed, %d skipped\n", created, followed, skipped)
+	return nil
+}
+
 func HandlerLogin(s *state.State, cmd Command) error {
 	if len(cmd.Args) < 1 {
 		return fmt.Errorf("Login requires one argument; zero provided.")
@@ -197,7 +395,20 @@ func HandlerLogin(s *state.State, cmd Command) error {
 		return err
 	}
 
-	err = s.Config.SetUser(existingUser.Name)
+	err = checkPassword(s, existingUser, "Password: ")
+	if err != nil {
+		return err
+	}
+
+	var token string
+	err = s.Db.InTx(context.Background(), func(qtx store.Store) error {
+		token, err = switchSession(context.Background(), qtx, s, existingUser)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	err = s.Config.SetSession(token)
 	if err != nil {
 		return err
 	}
@@ -205,6 +416,134 @@ func HandlerLogin(s *state.State, cmd Command) error {
 	return nil
 }
 
+// HandlerPasswd sets, changes or removes the current user's password. Every
+// other session of the user is logged out.
+func HandlerPasswd(s *state.State, cmd Command, user database.User) error {
+	if argLen := len(cmd.Args); argLen > 0 {
+		return fmt.Errorf("passwd takes no arguments; %d provided.", argLen)
+	}
+	err := checkPassword(s, user, "Current password: ")
+	if err != nil {
+		return err
+	}
+	newPassword, err := readNewPassword()
+	if err != nil {
+		return err
+	}
+
+	var token string
+	err = s.Db.InTx(context.Background(), func(qtx store.Store) error {
+		err := setPassword(context.Background(), qtx, user, newPassword)
+		if err != nil {
+			return err
+		}
+		err = qtx.DeleteSessionsForUser(context.Background(), user.ID)
+		if err != nil {
+			return err
+		}
+		token, err = switchSession(context.Background(), qtx, s, user)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	err = s.Config.SetSession(token)
+	if err != nil {
+		return err
+	}
+	if newPassword == "" {
+		fmt.Printf("Password removed for user '%s'\n", user.Name)
+		return nil
+	}
+	fmt.Printf("Password changed for user '%s'\n", user.Name)
+	return nil
+}
+
+func HandlerPublish(s *state.State, cmd Command, user database.User) error {
+	flags := flag.NewFlagSet("publish", flag.ContinueOnError)
+	format := flags.String("format", publish.FormatRSS, "feed format, rss or atom")
+	category := flags.String("category", "", "only publish posts from feeds in this category")
+	search := flags.String("search", "", "only publish posts matching this search")
+	limit := flags.Int("limit", publish.DefaultLimit, "maximum number of posts to publish")
+	userName := flags.String("user", "", "publish this user's timeline instead of the current user's, asking for their password")
+	link := flags.String("link", "", "URL the feed will be published at, required for rss")
+	secret := flags.Bool("secret", false, "create a new secret for the served feed URL, replacing the old one")
+	err := flags.Parse(cmd.Args)
+	if err != nil {
+		return err
+	}
+	if *userName != "" && *userName != user.Name {
+		if *secret {
+			return fmt.Errorf("--secret only replaces your own feed secret; log in as %s to replace theirs", *userName)
+		}
+		user, err = s.Db.GetUser(context.Background(), *userName)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("User '%s' does not exist", *userName)
+		} else if err != nil {
+			return err
+		}
+		err = checkPassword(s, user, fmt.Sprintf("Password for %s: ", user.Name))
+		if err != nil {
+			return err
+		}
+	}
+	if *secret {
+		token, hash, err := auth.NewToken()
+		if err != nil {
+			return err
+		}
+		err = s.Db.SetPublishToken(context.Background(), database.SetPublishTokenParams{
+			UserID: user.ID,
+			CreatedAt: time.Now().UTC(),
+			TokenHash: hash,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Feed secret for %s (shown only once):\n%s\n", user.Name, token)
+		fmt.Printf("Served at /publish/%s/rss and /publish/%s/atom\n", token, token)
+		return nil
+	}
+	if argLen := flags.NArg(); argLen > 1 {
+		return fmt.Errorf("publish has one optional argument; %d provided.", argLen)
+	}
+	if *format == publish.FormatRSS && *link == "" {
+		return fmt.Errorf("--link is required for %s feeds", publish.FormatRSS)
+	}
+	filter := publish.Filter{
+		Category: *category,
+		Search: *search,
+		Limit: *limit,
+	}
+	stream, err := publish.ForUser(context.Background(), s, user, filter)
+	if err != nil {
+		return err
+	}
+	stream.Link = *link
+	if flags.NArg() == 0 {
+		return stream.Write(os.Stdout, *format)
+	}
+	file, err := os.Create(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	err = stream.Write(file, *format)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Published %d posts to %s\n", len(stream.Entries), flags.Arg(0))
+	return nil
+}
+
+func HandlerRead(s *state.State, cmd Command, user database.User) error {
+	return setReadState(s, cmd, user, true)
+}
+
 func HandlerRegister(s *state.State, cmd Command) error {
 	if argLen := len(cmd.Args); argLen < 1 {
 		return fmt.Errorf("Register requires one argument; zero provided.")
@@ -213,23 +552,43 @@ func HandlerRegister(s *state.State, cmd Command) error {
 	}
 	utcTime := time.Now().UTC()
 	newUsername := cmd.Args[0]
+	_, err := s.Db.GetUser(context.Background(), newUsername)
+	if err == nil {
+		return fmt.Errorf("User %s already exists", newUsername)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	password, err := readNewPassword()
+	if err != nil {
+		return err
+	}
 	params := database.CreateUserParams {
 		ID: uuid.New(),
 		CreatedAt: utcTime,
 		UpdatedAt: utcTime,
 		Name: newUsername,
 	}
-	createUser, err := s.Db.CreateUser(context.Background(), params)
+	var createUser database.User
+	var token string
+	err = s.Db.InTx(context.Background(), func(qtx store.Store) error {
+		createUser, err = qtx.CreateUser(context.Background(), params)
+		if err != nil {
+			return err
+		}
+		err = setPassword(context.Background(), qtx, createUser, password)
+		if err != nil {
+			return err
+		}
+		token, err = switchSession(context.Background(), qtx, s, createUser)
+		return err
+	})
 	if err != nil {
-		var pqErr *pq.Error
-		if errors.As(err, &pqErr) {
-			if pqErr.Code == "23505" {
-				return fmt.Errorf("User %s already exists", newUsername)
-			}
+		if database.IsUniqueViolation(err) {
+			return fmt.Errorf("User %s already exists", newUsername)
 		}
 		return err
 	}
-	err = s.Config.SetUser(createUser.Name)
+	err = s.Config.SetSession(token)
 	if err != nil {
 		return err
 	}
@@ -237,10 +596,281 @@ func HandlerRegister(s *state.State, cmd Command) error {
 	return nil
 }
 
+// HandlerReset deletes everything, or with a flag only posts, one user or the
+// feeds' fetch state. It asks for confirmation unless --yes is given, and
+// writes a JSON backup of the database before deleting anything.
 func HandlerReset(s *state.State, cmd Command) error {
-	if err := s.Db.DeleteAllUsers(context.Background()); err != nil {
+	flags := flag.NewFlagSet("reset", flag.ContinueOnError)
+	yes := flags.Bool("yes", false, "reset without asking for confirmation")
+	posts := flags.Bool("posts", false, "only delete posts; starred posts are kept")
+	userName := flags.String("user", "", "only delete this user")
+	fetchState := flags.Bool("fetch-state", false, "only reset when feeds were fetched, so all are fetched again")
+	backupPath := flags.String("backup", "", "file to write the backup to")
+	err := flags.Parse(cmd.Args)
+	if err != nil {
 		return err
 	}
+	if argLen := flags.NArg(); argLen > 0 {
+		return fmt.Errorf("reset takes no arguments; %d provided.", argLen)
+	}
+	scopes := 0
+	for _, set := range []bool{*posts, *userName != "", *fetchState} {
+		if set {
+			scopes++
+		}
+	}
+	if scopes > 1 {
+		return fmt.Errorf("only one of --posts, --user and --fetch-state can be given")
+	}
+
+	ctx := context.Background()
+	var user database.User
+	var warning string
+	switch {
+	case *posts:
+		warning = "This deletes every post that is not starred, with its read state."
+	case *userName != "":
+		user, err = s.Db.GetUser(ctx, *userName)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("User '%s' does not exist", *userName)
+		} else if err != nil {
+			return err
+		}
+		warning = fmt.Sprintf("This deletes user '%s' with their follows, stars and tokens.", user.Name)
+	case *fetchState:
+		warning = "This resets the fetch state of every feed, so all feeds are fetched again."
+	default:
+		warning = "This deletes everything in the database: all users, feeds and posts."
+	}
+	if !*yes {
+		confirmed, err := confirm(warning)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return fmt.Errorf("Reset cancelled")
+		}
+	}
+
+	// Resetting fetch state loses no posts, so it needs no backup
+	destructive := !*fetchState
+	if destructive && *backupPath == "" {
+		*backupPath, err = backup.DefaultPath(time.Now())
+		if err != nil {
+			return err
+		}
+	}
+	err = s.Db.InTx(ctx, func(qtx store.Store) error {
+		if destructive {
+			// The backup is taken in the transaction, so it holds exactly
+			// what is deleted, and nothing is deleted if it cannot be written.
+			b, err := backup.Take(ctx, qtx)
+			if err != nil {
+				return err
+			}
+			err = b.WriteFile(*backupPath)
+			if err != nil {
+				return err
+			}
+		}
+		switch {
+		case *posts:
+			if err := qtx.DeleteUnstarredPosts(ctx); err != nil {
+				return err
+			}
+			// Without fetch state the deleted posts are fetched again
+			return qtx.ResetFeedFetchState(ctx)
+		case *userName != "":
+			// Feeds others follow are handed to them instead of deleted
+			if err := qtx.TransferFollowedFeeds(ctx, user.ID); err != nil {
+				return err
+			}
+			if err := qtx.DeleteUser(ctx, user.ID); err != nil {
+				return err
+			}
+			return qtx.DeleteOrphanedPosts(ctx)
+		case *fetchState:
+			return qtx.ResetFeedFetchState(ctx)
+		}
+		if err := qtx.DeleteAllUsers(ctx); err != nil {
+			return err
+		}
+		// Deleting users removes their feeds; posts no feed links to go too
+		return qtx.DeleteOrphanedPosts(ctx)
+	})
+	if err != nil {
+		return err
+	}
+	if destructive {
+		fmt.Printf("Backup written to %s\n", *backupPath)
+	}
+	fmt.Println("Reset complete")
+	return nil
+}
+
+func HandlerSaved(s *state.State, cmd Command, user database.User) error {
+	var postLimit int
+	var err error
+	if argLen := len(cmd.Args); argLen > 1 {
+		return fmt.Errorf("saved has one optional argument; %d provided.", argLen)
+	} else if argLen == 1 {
+		postLimit, err = strconv.Atoi(cmd.Args[0])
+		if err != nil {
+			return err
+		}
+	} else {
+		postLimit = 20
+	}
+	params := database.GetStarredPostsForUserParams{
+		UserID: user.ID,
+		Limit: int32(postLimit),
+	}
+	posts, err := s.Db.GetStarredPostsForUser(context.Background(), params)
+	if err != nil {
+		return err
+	}
+	for _, p := range posts {
+		fmt.Printf("[%s] %s: %s | %s\n", p.ID, p.Title.String, p.Url.String, p.PublishedAt.Time.String())
+	}
+	return nil
+}
+
+func HandlerSearch(s *state.State, cmd Command, user database.User) error {
+	flags := flag.NewFlagSet("search", flag.ContinueOnError)
+	feedURL := flags.String("feed", "", "only search posts from the feed with this url")
+	since := flags.String("since", "", "only search posts published on or after this date (YYYY-MM-DD)")
+	until := flags.String("until", "", "only search posts published on or before this date (YYYY-MM-DD)")
+	postLimit := flags.Int("limit", 10, "number of results to display")
+	err := flags.Parse(cmd.Args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() < 1 {
+		return fmt.Errorf("search requires a query; zero provided.")
+	}
+	params := database.SearchPostsForUserParams{
+		Query: strings.Join(flags.Args(), " "),
+		UserID: user.ID,
+		PostLimit: int32(*postLimit),
+	}
+	if *feedURL != "" {
+		feed, err := s.Db.GetFeed(context.Background(), *feedURL)
+		if err != nil {
+			return err
+		}
+		params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+	if *since != "" {
+		sinceDate, err := time.Parse(time.DateOnly, *since)
+		if err != nil {
+			return err
+		}
+		params.Since = sql.NullTime{Time: sinceDate, Valid: true}
+	}
+	if *until != "" {
+		untilDate, err := time.Parse(time.DateOnly, *until)
+		if err != nil {
+			return err
+		}
+		// until is inclusive of the whole day
+		params.Until = sql.NullTime{Time: untilDate.AddDate(0, 0, 1), Valid: true}
+	}
+	results, err := s.Db.SearchPostsForUser(context.Background(), params)
+	if err != nil {
+		return err
+	}
+	for _, r := range results {
+		fmt.Printf("[%s] %s: %s | %s\n", r.ID, r.Title.String, r.Url.String, r.PublishedAt.Time.String())
+		fmt.Printf("    %s\n", highlightSnippet(r.Snippet))
+	}
+	return nil
+}
+
+func HandlerServe(s *state.State, cmd Command) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := flags.String("addr", ":8080", "address to listen on")
+	err := flags.Parse(cmd.Args)
+	if err != nil {
+		return err
+	}
+	if argLen := flags.NArg(); argLen != 0 {
+		return fmt.Errorf("serve does not take any arguments: %d were provided", argLen)
+	}
+	httpServer := &http.Server{
+		Addr: *addr,
+		Handler: server.New(s).Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+	fmt.Printf("Serving gator on %s\n", *addr)
+	select {
+	case err = <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	fmt.Println("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return httpServer.Shutdown(shutdownCtx)
+}
+
+func HandlerStar(s *state.State, cmd Command, user database.User) error {
+	if argLen := len(cmd.Args); argLen < 1 {
+		return fmt.Errorf("star requires one argument; zero provided.")
+	} else if argLen > 1 {
+		return fmt.Errorf("star requires one argument; %d provided.", argLen)
+	}
+	post, err := findPost(s, user, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+	params := database.StarPostParams{
+		UserID: user.ID,
+		PostID: post.ID,
+		StarredAt: time.Now().UTC(),
+	}
+	return s.Db.StarPost(context.Background(), params)
+}
+
+func HandlerToken(s *state.State, cmd Command, user database.User) error {
+	flags := flag.NewFlagSet("token", flag.ContinueOnError)
+	revoke := flags.Bool("revoke", false, "revoke every API token of the current user")
+	err := flags.Parse(cmd.Args)
+	if err != nil {
+		return err
+	}
+	if *revoke {
+		return s.Db.DeleteAPITokensForUser(context.Background(), user.ID)
+	}
+	if argLen := flags.NArg(); argLen > 1 {
+		return fmt.Errorf("token has one optional argument; %d provided.", argLen)
+	}
+	name := flags.Arg(0)
+	if name == "" {
+		name = "default"
+	}
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		return err
+	}
+	params := database.CreateAPITokenParams{
+		ID: uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UserID: user.ID,
+		Name: name,
+		TokenHash: hash,
+		FeverKey: sql.NullString{String: auth.FeverKey(user.Name, token), Valid: true},
+	}
+	_, err = s.Db.CreateAPIToken(context.Background(), params)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("API token '%s' for %s (shown only once):\n%s\n", name, user.Name, token)
 	return nil
 }
 
@@ -262,13 +892,38 @@ func HandlerUnfollow(s *state.State, cmd Command, user database.User) error {
 	return nil
 }
 
+func HandlerUnread(s *state.State, cmd Command, user database.User) error {
+	return setReadState(s, cmd, user, false)
+}
+
+func HandlerUnstar(s *state.State, cmd Command, user database.User) error {
+	if argLen := len(cmd.Args); argLen < 1 {
+		return fmt.Errorf("unstar requires one argument; zero provided.")
+	} else if argLen > 1 {
+		return fmt.Errorf("unstar requires one argument; %d provided.", argLen)
+	}
+	// Stars outlive follows, so posts outside the user's feeds can still be
+	// unstarred; this only removes the user's own star
+	post, err := lookupPost(s, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+	params := database.UnstarPostParams{
+		UserID: user.ID,
+		PostID: post.ID,
+	}
+	return s.Db.UnstarPost(context.Background(), params)
+}
+
 func HandlerUsers(s *state.State, cmd Command) error {
 	usernames, err := s.Db.GetAllUsers(context.Background())
 	if err != nil {
 		return err
 	}
+	// Listing users does not need a login, so an unknown session is no error
+	current, _ := CurrentUser(s)
 	for _, name := range usernames {
-		if name == s.Config.Current_user_name {
+		if name == current.Name {
 			fmt.Printf("* %s (current)\n", name)
 			continue
 		}
@@ -276,3 +931,244 @@ func HandlerUsers(s *state.State, cmd Command) error {
 	}
 	return nil
 }
+
+// setReadState marks a single post, or every post of a feed with --feed, as
+// read or unread for the user. Posts are named by the id shown in browse or
+// by their url, and must be in the feeds the user follows.
+func setReadState(s *state.State, cmd Command, user database.User, read bool) error {
+	flags := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	feedURL := flags.String("feed", "", "mark every post of the feed with this url")
+	err := flags.Parse(cmd.Args)
+	if err != nil {
+		return err
+	}
+	utcTime := time.Now().UTC()
+	if *feedURL != "" {
+		if argLen := flags.NArg(); argLen != 0 {
+			return fmt.Errorf("%s --feed takes no other arguments; %d provided.", cmd.Name, argLen)
+		}
+		following, err := s.Db.GetFeedFollowsForUser(context.Background(), user.Name)
+		if err != nil {
+			return err
+		}
+		var feedID uuid.UUID
+		for _, f := range following {
+			if f.Feedurl == *feedURL {
+				feedID = f.FeedID
+			}
+		}
+		if feedID == uuid.Nil {
+			return fmt.Errorf("Feed '%s' is not in your feeds", *feedURL)
+		}
+		if read {
+			params := database.MarkFeedReadParams{
+				UserID: user.ID,
+				ReadAt: utcTime,
+				FeedID: feedID,
+			}
+			return s.Db.MarkFeedRead(context.Background(), params)
+		}
+		params := database.MarkFeedUnreadParams{
+			UserID: user.ID,
+			FeedID: feedID,
+		}
+		return s.Db.MarkFeedUnread(context.Background(), params)
+	}
+	if argLen := flags.NArg(); argLen < 1 {
+		return fmt.Errorf("%s requires one argument; zero provided.", cmd.Name)
+	} else if argLen > 1 {
+		return fmt.Errorf("%s requires one argument; %d provided.", cmd.Name, argLen)
+	}
+	post, err := findPost(s, user, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if read {
+		params := database.MarkPostReadParams{
+			UserID: user.ID,
+			PostID: post.ID,
+			ReadAt: utcTime,
+		}
+		return s.Db.MarkPostRead(context.Background(), params)
+	}
+	params := database.MarkPostUnreadParams{
+		UserID: user.ID,
+		PostID: post.ID,
+	}
+	return s.Db.MarkPostUnread(context.Background(), params)
+}
+
+// CurrentUser returns the user whose session token is in the config
+func CurrentUser(s *state.State) (database.User, error) {
+	if s.Config.Session_token == "" {
+		return database.User{}, fmt.Errorf("Not logged in; run gator login <name>")
+	}
+	user, err := s.Db.GetUserFromSession(context.Background(), auth.HashToken(s.Config.Session_token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return user, fmt.Errorf("Session has expired; run gator login <name>")
+	}
+	return user, err
+}
+
+// switchSession ends the session in the config, if any, and starts a new one
+// for user. It returns the token to store in the config.
+func switchSession(ctx context.Context, db store.Store, s *state.State, user database.User) (string, error) {
+	if s.Config.Session_token != "" {
+		err := db.DeleteSession(ctx, auth.HashToken(s.Config.Session_token))
+		if err != nil {
+			return "", err
+		}
+	}
+	token, tokenHash, err := auth.NewToken()
+	if err != nil {
+		return "", err
+	}
+	params := database.CreateSessionParams{
+		ID: uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UserID: user.ID,
+		TokenHash: tokenHash,
+	}
+	_, err = db.CreateSession(ctx, params)
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// setPassword stores a new password for user; an empty password removes it
+func setPassword(ctx context.Context, db store.Store, user database.User, password string) error {
+	if password == "" {
+		return db.DeleteUserPassword(ctx, user.ID)
+	}
+	passwordHash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	params := database.SetUserPasswordParams{
+		UserID: user.ID,
+		UpdatedAt: time.Now().UTC(),
+		PasswordHash: passwordHash,
+	}
+	return db.SetUserPassword(ctx, params)
+}
+
+// stdin is shared by the password prompts so that piped input is not lost
+// between them.
+var stdin = bufio.NewReader(os.Stdin)
+
+// checkPassword asks for a user's password when they have one and fails
+// unless it matches. Users without a password pass unchecked.
+func checkPassword(s *state.State, user database.User, prompt string) error {
+	passwordHash, err := s.Db.GetUserPasswordHash(context.Background(), user.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	password, err := readPassword(prompt)
+	if err != nil {
+		return err
+	}
+	err = auth.CheckPassword(passwordHash, password)
+	if errors.Is(err, auth.ErrWrongPassword) {
+		return fmt.Errorf("Wrong password for user '%s'", user.Name)
+	}
+	return err
+}
+
+// readPassword prompts for a password. The password is not echoed when gator
+// runs in a terminal; otherwise a line is read from standard input.
+func readPassword(prompt string) (string, error) {
+	fmt.Print(prompt)
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		password, err := term.ReadPassword(fd)
+		fmt.Println()
+		return string(password), err
+	}
+	line, err := stdin.ReadString('\n')
+	fmt.Println()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// confirm shows a warning and asks the user to type yes to go ahead
+func confirm(warning string) (bool, error) {
+	fmt.Println(warning)
+	fmt.Print("Type yes to continue: ")
+	line, err := stdin.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "yes" || answer == "y", nil
+}
+
+// readNewPassword prompts for a new password twice. An empty password means
+// the user has none.
+func readNewPassword() (string, error) {
+	password, err := readPassword("New password (leave empty for none): ")
+	if err != nil || password == "" {
+		return "", err
+	}
+	confirm, err := readPassword("Confirm new password: ")
+	if err != nil {
+		return "", err
+	}
+	if password != confirm {
+		return "", fmt.Errorf("Passwords do not match")
+	}
+	return password, nil
+}
+
+// lookupPost looks up any post by id, falling back to its url as given or
+// normalized
+func lookupPost(s *state.State, ref string) (database.Post, error) {
+	var post database.Post
+	var err error
+	if id, parseErr := uuid.Parse(ref); parseErr == nil {
+		post, err = s.Db.GetPost(context.Background(), id)
+	} else {
+		post, err = s.Db.GetPostByURL(context.Background(), sql.NullString{String: ref, Valid: true})
+		if errors.Is(err, sql.ErrNoRows) {
+			// Links are stored normalized
+			post, err = s.Db.GetPostByURL(context.Background(), sql.NullString{String: feed.NormalizeURL(ref), Valid: true})
+		}
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return post, fmt.Errorf("Post '%s' does not exist", ref)
+	}
+	return post, err
+}
+
+// findPost looks up a post like lookupPost, but only in the feeds the user
+// follows
+func findPost(s *state.State, user database.User, ref string) (database.Post, error) {
+	post, err := lookupPost(s, ref)
+	if err != nil {
+		return post, err
+	}
+	inFeeds, err := s.Db.PostInUserFeeds(context.Background(), database.PostInUserFeedsParams{PostID: post.ID, UserID: user.ID})
+	if err != nil {
+		return post, err
+	}
+	if !inFeeds {
+		return post, fmt.Errorf("Post '%s' is not in your feeds", ref)
+	}
+	return post, nil
+}
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// highlightSnippet turns a search headline into terminal output. Markup from
+// the post is dropped and the matched terms are shown in bold.
+func highlightSnippet(snippet string) string {
+	snippet = html.UnescapeString(htmlTag.ReplaceAllString(snippet, ""))
+	snippet = strings.Join(strings.Fields(snippet), " ")
+	snippet = strings.ReplaceAll(snippet, database.HeadlineStart, "\033[1m")
+	snippet = strings.ReplaceAll(snippet, database.HeadlineStop, "\033[0m")
+	return snippet
+}
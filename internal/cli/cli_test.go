@@ -0,0 +1,486 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theMagicRabbit/gator/internal/config"
+	"github.com/theMagicRabbit/gator/internal/database"
+	"github.com/theMagicRabbit/gator/internal/opml"
+	"github.com/theMagicRabbit/gator/internal/state"
+	"github.com/theMagicRabbit/gator/internal/store"
+)
+
+// newTestState returns a state backed by an empty memory store. HOME is a
+// temporary directory, so the config and backups are written there.
+func newTestState(t *testing.T) *state.State {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	return &state.State{Config: &config.Config{}, Db: store.NewMemory()}
+}
+
+// setInput makes the prompts read the given lines
+func setInput(t *testing.T, lines ...string) {
+	t.Helper()
+	saved := stdin
+	stdin = bufio.NewReader(strings.NewReader(strings.Join(lines, "\n") + "\n"))
+	t.Cleanup(func() { stdin = saved })
+}
+
+func run(t *testing.T, s *state.State, handler func(*state.State, Command) error, name string, args ...string) {
+	t.Helper()
+	err := handler(s, Command{Name: name, Args: args})
+	if err != nil {
+		t.Fatalf("%s %s: %v", name, strings.Join(args, " "), err)
+	}
+}
+
+func runAs(t *testing.T, s *state.State, handler func(*state.State, Command, database.User) error, name string, args ...string) {
+	t.Helper()
+	user, err := CurrentUser(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = handler(s, Command{Name: name, Args: args}, user)
+	if err != nil {
+		t.Fatalf("%s %s: %v", name, strings.Join(args, " "), err)
+	}
+}
+
+// register creates a user without a password and logs them in
+func register(t *testing.T, s *state.State, name string) database.User {
+	t.Helper()
+	setInput(t, "")
+	run(t, s, HandlerRegister, "register", name)
+	user, err := CurrentUser(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func followedURLs(t *testing.T, s *state.State, user database.User) []string {
+	t.Helper()
+	follows, err := s.Db.GetFeedFollowsForUser(context.Background(), user.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var urls []string
+	for _, follow := range follows {
+		urls = append(urls, follow.Feedurl)
+	}
+	slices.Sort(urls)
+	return urls
+}
+
+func TestRegisterLoginPasswd(t *testing.T) {
+	s := newTestState(t)
+	setInput(t, "secret", "secret")
+	run(t, s, HandlerRegister, "register", "alice")
+	if user, err := CurrentUser(s); err != nil || user.Name != "alice" {
+		t.Fatalf("current user is %q, %v; want alice", user.Name, err)
+	}
+	saved, err := config.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Session_token != s.Config.Session_token {
+		t.Error("session token was not written to the config file")
+	}
+
+	setInput(t, "secret", "secret")
+	if err := HandlerRegister(s, Command{Name: "register", Args: []string{"alice"}}); err == nil {
+		t.Error("registering alice twice succeeded")
+	}
+
+	register(t, s, "bob")
+	setInput(t, "wrong")
+	if err := HandlerLogin(s, Command{Name: "login", Args: []string{"alice"}}); err == nil {
+		t.Error("login with a wrong password succeeded")
+	}
+	if user, _ := CurrentUser(s); user.Name != "bob" {
+		t.Errorf("failed login switched the user to %q", user.Name)
+	}
+	setInput(t, "secret")
+	run(t, s, HandlerLogin, "login", "alice")
+	if user, _ := CurrentUser(s); user.Name != "alice" {
+		t.Errorf("current user is %q, want alice", user.Name)
+	}
+
+	// Changing the password keeps this session and ends every other one
+	oldToken := s.Config.Session_token
+	setInput(t, "secret", "changed", "changed")
+	runAs(t, s, HandlerPasswd, "passwd")
+	if s.Config.Session_token == oldToken {
+		t.Error("passwd kept the old session token")
+	}
+	if user, err := CurrentUser(s); err != nil || user.Name != "alice" {
+		t.Errorf("current user after passwd is %q, %v; want alice", user.Name, err)
+	}
+	setInput(t, "secret")
+	if err := HandlerLogin(s, Command{Name: "login", Args: []string{"alice"}}); err == nil {
+		t.Error("login with the old password succeeded")
+	}
+}
+
+func TestAddFeedFollowUnfollow(t *testing.T) {
+	s := newTestState(t)
+	alice := register(t, s, "alice")
+	runAs(t, s, HandlerAddFeed, "addfeed", "Blog", "https://example.com/feed.xml")
+	if urls := followedURLs(t, s, alice); !slices.Equal(urls, []string{"https://example.com/feed.xml"}) {
+		t.Errorf("alice follows %v after addfeed", urls)
+	}
+	err := HandlerAddFeed(s, Command{Name: "addfeed", Args: []string{"Again", "https://example.com/feed.xml"}}, alice)
+	if err == nil {
+		t.Error("adding the same feed twice succeeded")
+	}
+
+	bob := register(t, s, "bob")
+	runAs(t, s, HandlerFollow, "follow", "https://example.com/feed.xml")
+	if urls := followedURLs(t, s, bob); len(urls) != 1 {
+		t.Errorf("bob follows %v after follow", urls)
+	}
+	runAs(t, s, HandlerUnfollow, "unfollow", "https://example.com/feed.xml")
+	if urls := followedURLs(t, s, bob); len(urls) != 0 {
+		t.Errorf("bob follows %v after unfollow", urls)
+	}
+	if urls := followedURLs(t, s, alice); len(urls) != 1 {
+		t.Errorf("bob's unfollow changed alice's follows to %v", urls)
+	}
+}
+
+const testOPML = `<?xml version="1.0"?>
+<opml version="2.0">
+  <head><title>Subscriptions</title></head>
+  <body>
+    <outline text="Go" title="Go">
+      <outline type="rss" text="Go Blog" xmlUrl="https://go.dev/blog/feed.atom"/>
+    </outline>
+    <outline type="rss" text="Example" xmlUrl="https://example.com/feed.xml"/>
+  </body>
+</opml>
+`
+
+func TestImportExport(t *testing.T) {
+	s := newTestState(t)
+	alice := register(t, s, "alice")
+	path := filepath.Join(t.TempDir(), "subscriptions.opml")
+	err := os.WriteFile(path, []byte(testOPML), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runAs(t, s, HandlerImport, "import", path)
+	// Importing again skips the feeds already followed
+	runAs(t, s, HandlerImport, "import", path)
+	want := []string{"https://example.com/feed.xml", "https://go.dev/blog/feed.atom"}
+	if urls := followedURLs(t, s, alice); !slices.Equal(urls, want) {
+		t.Errorf("alice follows %v, want %v", urls, want)
+	}
+	feeds, err := s.Db.GetAllFeeds(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feeds) != 2 {
+		t.Errorf("%d feeds after importing twice, want 2", len(feeds))
+	}
+
+	exported := filepath.Join(t.TempDir(), "export.opml")
+	runAs(t, s, HandlerExport, "export", exported)
+	file, err := os.Open(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	doc, err := opml.Parse(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	categories := map[string]string{}
+	for _, sub := range doc.Subscriptions() {
+		categories[sub.URL] = sub.Category
+	}
+	if len(categories) != 2 || categories["https://go.dev/blog/feed.atom"] != "Go" || categories["https://example.com/feed.xml"] != "" {
+		t.Errorf("exported subscriptions are %v", categories)
+	}
+}
+
+func TestResetUser(t *testing.T) {
+	s := newTestState(t)
+	alice := register(t, s, "alice")
+	runAs(t, s, HandlerAddFeed, "addfeed", "Shared", "https://example.com/shared.xml")
+	runAs(t, s, HandlerAddFeed, "addfeed", "Own", "https://example.com/own.xml")
+	bob := register(t, s, "bob")
+	runAs(t, s, HandlerFollow, "follow", "https://example.com/shared.xml")
+
+	backupPath := filepath.Join(t.TempDir(), "backup.json")
+	run(t, s, HandlerReset, "reset", "--yes", "--user", "alice", "--backup", backupPath)
+	users, err := s.Db.GetAllUsers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(users, []string{"bob"}) {
+		t.Errorf("users after reset are %v, want [bob]", users)
+	}
+	shared, err := s.Db.GetFeed(context.Background(), "https://example.com/shared.xml")
+	if err != nil {
+		t.Fatalf("feed bob follows was deleted with alice: %v", err)
+	}
+	if shared.UserID != bob.ID {
+		t.Error("feed bob follows was not handed to him")
+	}
+	if _, err := s.Db.GetFeed(context.Background(), "https://example.com/own.xml"); err == nil {
+		t.Error("feed only alice followed was kept")
+	}
+	backup, err := os.ReadFile(backupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(backup), alice.ID.String()) {
+		t.Error("backup does not hold alice")
+	}
+}
+
+func TestResetAsksForConfirmation(t *testing.T) {
+	s := newTestState(t)
+	register(t, s, "alice")
+	setInput(t, "no")
+	if err := HandlerReset(s, Command{Name: "reset"}); err == nil {
+		t.Error("reset went ahead without confirmation")
+	}
+	if users, _ := s.Db.GetAllUsers(context.Background()); len(users) != 1 {
+		t.Errorf("users after a cancelled reset are %v", users)
+	}
+
+	setInput(t, "yes")
+	run(t, s, HandlerReset, "reset")
+	if users, _ := s.Db.GetAllUsers(context.Background()); len(users) != 0 {
+		t.Errorf("users after reset are %v", users)
+	}
+	backups, err := filepath.Glob(filepath.Join(os.Getenv("HOME"), ".gator-backup-*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 {
+		t.Errorf("found backups %v, want one in HOME", backups)
+	}
+}
+
+func publishTokens(t *testing.T, s *state.State) map[uuid.UUID]string {
+	t.Helper()
+	tokens, err := s.Db.BackupPublishTokens(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes := map[uuid.UUID]string{}
+	for _, token := range tokens {
+		hashes[token.UserID] = token.TokenHash
+	}
+	return hashes
+}
+
+func TestPublishOtherUser(t *testing.T) {
+	s := newTestState(t)
+	setInput(t, "secret", "secret")
+	run(t, s, HandlerRegister, "register", "alice")
+	alice, err := CurrentUser(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runAs(t, s, HandlerPublish, "publish", "--secret")
+	aliceToken := publishTokens(t, s)[alice.ID]
+	bob := register(t, s, "bob")
+	out := filepath.Join(t.TempDir(), "alice.xml")
+
+	// Another user's timeline needs their password
+	setInput(t, "wrong")
+	if err := HandlerPublish(s, Command{Name: "publish", Args: []string{"--user", "alice", "--link", "https://example.com/alice.xml", out}}, bob); err == nil {
+		t.Error("publishing alice's timeline with a wrong password succeeded")
+	}
+	if _, err := os.Stat(out); err == nil {
+		t.Error("a wrong password still wrote alice's timeline")
+	}
+	setInput(t, "secret")
+	runAs(t, s, HandlerPublish, "publish", "--user", "alice", "--link", "https://example.com/alice.xml", out)
+	if _, err := os.Stat(out); err != nil {
+		t.Errorf("alice's timeline was not written: %v", err)
+	}
+
+	// Secrets can only be replaced for the current user
+	setInput(t, "secret")
+	if err := HandlerPublish(s, Command{Name: "publish", Args: []string{"--secret", "--user", "alice"}}, bob); err == nil {
+		t.Error("bob replaced alice's feed secret")
+	}
+	runAs(t, s, HandlerPublish, "publish", "--secret", "--user", "bob")
+	tokens := publishTokens(t, s)
+	if tokens[alice.ID] != aliceToken {
+		t.Error("alice's feed secret changed")
+	}
+	if tokens[bob.ID] == "" {
+		t.Error("bob's feed secret was not created")
+	}
+
+	// Users without a password are published without a prompt
+	setInput(t, "secret")
+	run(t, s, HandlerLogin, "login", "alice")
+	setInput(t)
+	runAs(t, s, HandlerPublish, "publish", "--user", "bob", "--format", "atom", filepath.Join(t.TempDir(), "bob.xml"))
+}
+
+func TestPublishRSSNeedsLink(t *testing.T) {
+	s := newTestState(t)
+	user := register(t, s, "gator")
+	dir := t.TempDir()
+
+	err := HandlerPublish(s, Command{Name: "publish", Args: []string{filepath.Join(dir, "feed.xml")}}, user)
+	if err == nil {
+		t.Error("publishing rss without --link succeeded")
+	}
+	runAs(t, s, HandlerPublish, "publish", "--format", "atom", filepath.Join(dir, "feed.atom"))
+	runAs(t, s, HandlerPublish, "publish", "--link", "https://example.com/feed.xml", filepath.Join(dir, "feed.xml"))
+	data, err := os.ReadFile(filepath.Join(dir, "feed.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "<link>https://example.com/feed.xml</link>") {
+		t.Errorf("rss feed has no channel link:\n%s", data)
+	}
+}
+
+// addPost stores a post in the feed with the given url
+func addPost(t *testing.T, s *state.State, feedURL, title string) database.Post {
+	t.Helper()
+	ctx := context.Background()
+	f, err := s.Db.GetFeed(ctx, feedURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	post, err := s.Db.CreatePost(ctx, database.CreatePostParams{
+		ID: uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Title: sql.NullString{String: title, Valid: true},
+		Url: sql.NullString{String: "https://example.com/" + title, Valid: true},
+		PublishedAt: sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Db.CreatePostFeed(ctx, database.CreatePostFeedParams{PostID: post.ID, FeedID: f.ID, Guid: title, CreatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return post
+}
+
+// readTitles returns the titles of the user's posts that are read
+func readTitles(t *testing.T, s *state.State, user database.User) []string {
+	t.Helper()
+	posts, err := s.Db.GetPostsForUser(context.Background(), database.GetPostsForUserParams{UserID: user.ID, IncludeRead: true, PostLimit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, post := range posts {
+		if post.IsRead {
+			titles = append(titles, post.Title.String)
+		}
+	}
+	slices.Sort(titles)
+	return titles
+}
+
+func TestReadOnlyOwnFeeds(t *testing.T) {
+	s := newTestState(t)
+	register(t, s, "alice")
+	runAs(t, s, HandlerAddFeed, "addfeed", "Alice", "https://example.com/alice.xml")
+	secret := addPost(t, s, "https://example.com/alice.xml", "secret")
+	bob := register(t, s, "bob")
+	runAs(t, s, HandlerAddFeed, "addfeed", "Bob", "https://example.com/bob.xml")
+	own := addPost(t, s, "https://example.com/bob.xml", "own")
+	other := addPost(t, s, "https://example.com/bob.xml", "other")
+
+	runAs(t, s, HandlerRead, "read", own.ID.String())
+	runAs(t, s, HandlerRead, "read", other.Url.String)
+	if titles := readTitles(t, s, bob); !slices.Equal(titles, []string{"other", "own"}) {
+		t.Errorf("bob read %q, want other and own", titles)
+	}
+	runAs(t, s, HandlerUnread, "unread", other.ID.String())
+	if titles := readTitles(t, s, bob); !slices.Equal(titles, []string{"own"}) {
+		t.Errorf("bob read %q after unread, want own", titles)
+	}
+
+	for _, ref := range []string{secret.ID.String(), secret.Url.String} {
+		for name, handler := range map[string]func(*state.State, Command, database.User) error{"read": HandlerRead, "unread": HandlerUnread} {
+			err := handler(s, Command{Name: name, Args: []string{ref}}, bob)
+			if err == nil || !strings.Contains(err.Error(), "not in your feeds") {
+				t.Errorf("%s %s of alice's post got %v, want not in your feeds", name, ref, err)
+			}
+		}
+	}
+	err := HandlerRead(s, Command{Name: "read", Args: []string{"--feed", "https://example.com/alice.xml"}}, bob)
+	if err == nil || !strings.Contains(err.Error(), "not in your feeds") {
+		t.Errorf("read --feed of alice's feed got %v, want not in your feeds", err)
+	}
+	runAs(t, s, HandlerRead, "read", "--feed", "https://example.com/bob.xml")
+	if titles := readTitles(t, s, bob); !slices.Equal(titles, []string{"other", "own"}) {
+		t.Errorf("bob read %q after read --feed, want other and own", titles)
+	}
+}
+
+func starredTitles(t *testing.T, s *state.State, user database.User) []string {
+	t.Helper()
+	posts, err := s.Db.GetStarredPostsForUser(context.Background(), database.GetStarredPostsForUserParams{UserID: user.ID, Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, post := range posts {
+		titles = append(titles, post.Title.String)
+	}
+	return titles
+}
+
+func TestStarOnlyOwnFeeds(t *testing.T) {
+	s := newTestState(t)
+	register(t, s, "alice")
+	runAs(t, s, HandlerAddFeed, "addfeed", "Alice", "https://example.com/alice.xml")
+	secret := addPost(t, s, "https://example.com/alice.xml", "secret")
+	bob := register(t, s, "bob")
+	runAs(t, s, HandlerAddFeed, "addfeed", "Bob", "https://example.com/bob.xml")
+	own := addPost(t, s, "https://example.com/bob.xml", "own")
+
+	runAs(t, s, HandlerStar, "star", own.Url.String)
+	for _, ref := range []string{secret.ID.String(), secret.Url.String} {
+		err := HandlerStar(s, Command{Name: "star", Args: []string{ref}}, bob)
+		if err == nil || !strings.Contains(err.Error(), "not in your feeds") {
+			t.Errorf("star %s of alice's post got %v, want not in your feeds", ref, err)
+		}
+	}
+	if titles := starredTitles(t, s, bob); !slices.Equal(titles, []string{"own"}) {
+		t.Errorf("bob starred %q, want own", titles)
+	}
+
+	// A starred post can still be unstarred after its feed is unfollowed
+	runAs(t, s, HandlerUnfollow, "unfollow", "https://example.com/bob.xml")
+	runAs(t, s, HandlerUnstar, "unstar", own.ID.String())
+	if titles := starredTitles(t, s, bob); len(titles) != 0 {
+		t.Errorf("bob starred %q after unstar, want none", titles)
+	}
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	snippet := "<p>Il a dit «\x02bonjour\x03»</p>\n&amp;  \x02adieu\x03"
+	want := "Il a dit «\033[1mbonjour\033[0m» & \033[1madieu\033[0m"
+	if got := highlightSnippet(snippet); got != want {
+		t.Errorf("highlightSnippet is %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/theMagicRabbit/gator/internal/database"
+	"github.com/theMagicRabbit/gator/internal/store"
+)
+
+var errInjected = errors.New("injected failure")
+
+// failingStore fails a chosen call to CreateFeedFollows or DeleteUser, to
+// check that the writes before it in the transaction are rolled back.
+type failingStore struct {
+	store.Store
+	method	string
+	// failAt is the call of method that fails, counting from 1
+	failAt	int
+	calls	*int
+}
+
+func newFailingStore(db store.Store, method string, failAt int) failingStore {
+	return failingStore{Store: db, method: method, failAt: failAt, calls: new(int)}
+}
+
+func (f failingStore) fail(method string) bool {
+	if method != f.method {
+		return false
+	}
+	*f.calls++
+	return *f.calls == f.failAt
+}
+
+func (f failingStore) InTx(ctx context.Context, fn func(store.Store) error) error {
+	return f.Store.InTx(ctx, func(tx store.Store) error {
+		wrapped := f
+		wrapped.Store = tx
+		return fn(wrapped)
+	})
+}
+
+func (f failingStore) CreateFeedFollows(ctx context.Context, arg database.CreateFeedFollowsParams) (database.CreateFeedFollowsRow, error) {
+	if f.fail("CreateFeedFollows") {
+		return database.CreateFeedFollowsRow{}, errInjected
+	}
+	return f.Store.CreateFeedFollows(ctx, arg)
+}
+
+func (f failingStore) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	if f.fail("DeleteUser") {
+		return errInjected
+	}
+	return f.Store.DeleteUser(ctx, id)
+}
+
+func TestAddFeedRollsBackWithoutFollow(t *testing.T) {
+	s := newTestState(t)
+	alice := register(t, s, "alice")
+	db := s.Db
+	s.Db = newFailingStore(db, "CreateFeedFollows", 1)
+	err := HandlerAddFeed(s, Command{Name: "addfeed", Args: []string{"Blog", "https://example.com/feed.xml"}}, alice)
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("addfeed returned %v, want the injected failure", err)
+	}
+	s.Db = db
+	if _, err := db.GetFeed(context.Background(), "https://example.com/feed.xml"); err == nil {
+		t.Error("feed was kept although following it failed")
+	}
+}
+
+func TestImportRollsBack(t *testing.T) {
+	s := newTestState(t)
+	alice := register(t, s, "alice")
+	path := filepath.Join(t.TempDir(), "subscriptions.opml")
+	err := os.WriteFile(path, []byte(testOPML), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := s.Db
+	s.Db = newFailingStore(db, "CreateFeedFollows", 2)
+	err = HandlerImport(s, Command{Name: "import", Args: []string{path}}, alice)
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("import returned %v, want the injected failure", err)
+	}
+	s.Db = db
+	if urls := followedURLs(t, s, alice); len(urls) != 0 {
+		t.Errorf("alice follows %v after a failed import", urls)
+	}
+	if feeds, _ := db.GetAllFeeds(context.Background()); len(feeds) != 0 {
+		t.Errorf("%d feeds kept after a failed import", len(feeds))
+	}
+}
+
+func TestResetUserRollsBack(t *testing.T) {
+	s := newTestState(t)
+	register(t, s, "alice")
+	runAs(t, s, HandlerAddFeed, "addfeed", "Shared", "https://example.com/shared.xml")
+	bob := register(t, s, "bob")
+	runAs(t, s, HandlerFollow, "follow", "https://example.com/shared.xml")
+
+	db := s.Db
+	s.Db = newFailingStore(db, "DeleteUser", 1)
+	backupPath := filepath.Join(t.TempDir(), "backup.json")
+	err := HandlerReset(s, Command{Name: "reset", Args: []string{"--yes", "--user", "alice", "--backup", backupPath}})
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("reset returned %v, want the injected failure", err)
+	}
+	s.Db = db
+	if users, _ := db.GetAllUsers(context.Background()); len(users) != 2 {
+		t.Errorf("users after a failed reset are %v", users)
+	}
+	// Handing the feed to bob happened before the failure and is undone
+	feed, err := db.GetFeed(context.Background(), "https://example.com/shared.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.UserID == bob.ID {
+		t.Error("feed was handed to bob although the reset failed")
+	}
+}
@@ -10,7 +10,7 @@ const configJsonName string = ".gatorconfig.json"
 
 type Config struct {
 	Db_url string;
-	Current_user_name string;
+	Session_token string;
 }
 
 // generateConfigFilePath generates the full path name for the config file
@@ -39,18 +39,22 @@ func Read() (Config, error) {
 	return config, nil
 }
 
-// SetUser sets the current user value and writes current configuration to
-// the config file.
-func(c Config) SetUser(current_user string) error {
-	c.Current_user_name = current_user
+// SetSession sets the session token of the logged in user and writes current
+// configuration to the config file. The file is only readable by its owner
+// as the token logs in without a password.
+func(c *Config) SetSession(token string) error {
+	c.Session_token = token
 	configData, err := json.Marshal(c)
 	if err != nil {
 		return err
 	}
 	configFileName, err := generateConfigFilePath()
-	err = os.WriteFile(configFileName, configData, 0644)
 	if err != nil {
 		return err
 	}
-	return nil
+	err = os.WriteFile(configFileName, configData, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Chmod(configFileName, 0600)
 }
@@ -0,0 +1,31 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// ErrUniqueViolation is returned by stores other than the databases when a
+// write would break a unique constraint.
+var ErrUniqueViolation = errors.New("unique constraint violation")
+
+// IsUniqueViolation reports whether err is a unique constraint violation
+// from either database backend or ErrUniqueViolation.
+func IsUniqueViolation(err error) bool {
+	if errors.Is(err, ErrUniqueViolation) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		code := sqliteErr.Code()
+		return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+	return false
+}
@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,11 +15,11 @@ import (
 
 const createFeedFollows = `-- name: CreateFeedFollows :one
 WITH ins AS (
-    INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
-        VALUES($1, $2, $3, $4, $5)
-    RETURNING id, created_at, updated_at, user_id, feed_id
+    INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, category)
+        VALUES($1, $2, $3, $4, $5, $6)
+    RETURNING id, created_at, updated_at, user_id, feed_id, category
 )
-SELECT ins.id, ins.created_at, ins.updated_at, ins.user_id, ins.feed_id, users.name AS username, feeds.name AS feedname FROM ins
+SELECT ins.id, ins.created_at, ins.updated_at, ins.user_id, ins.feed_id, ins.category, users.name AS username, feeds.name AS feedname FROM ins
 JOIN users ON users.id = ins.user_id
 JOIN feeds ON feeds.id = ins.feed_id
 `
@@ -29,6 +30,7 @@ type CreateFeedFollowsParams struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Category  sql.NullString
 }
 
 type CreateFeedFollowsRow struct {
@@ -37,6 +39,7 @@ type CreateFeedFollowsRow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Category  sql.NullString
 	Username  string
 	Feedname  string
 }
@@ -48,6 +51,7 @@ func (q *Queries) CreateFeedFollows(ctx context.Context, arg CreateFeedFollowsPa
 		arg.UpdatedAt,
 		arg.UserID,
 		arg.FeedID,
+		arg.Category,
 	)
 	var i CreateFeedFollowsRow
 	err := row.Scan(
@@ -56,6 +60,7 @@ func (q *Queries) CreateFeedFollows(ctx context.Context, arg CreateFeedFollowsPa
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.Category,
 		&i.Username,
 		&i.Feedname,
 	)
@@ -78,16 +83,45 @@ func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowPara
 	return err
 }
 
+const getFeedFollow = `-- name: GetFeedFollow :one
+SELECT id, created_at, updated_at, user_id, feed_id, category FROM feed_follows
+WHERE user_id = $1
+AND feed_id = $2
+`
+
+type GetFeedFollowParams struct {
+	UserID uuid.UUID
+	FeedID uuid.UUID
+}
+
+func (q *Queries) GetFeedFollow(ctx context.Context, arg GetFeedFollowParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedFollow, arg.UserID, arg.FeedID)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.Category,
+	)
+	return i, err
+}
+
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
-SELECT feeds.name AS feedname, users.name AS username FROM feed_follows
+SELECT feeds.name AS feedname, users.name AS username, feeds.url AS feedurl, feed_follows.category, feed_follows.feed_id FROM feed_follows
     JOIN users ON users.id = feed_follows.user_id
     JOIN feeds ON feeds.id = feed_follows.feed_id
 WHERE users.name = $1
+ORDER BY feed_follows.category NULLS FIRST, feeds.name
 `
 
 type GetFeedFollowsForUserRow struct {
 	Feedname string
 	Username string
+	Feedurl  string
+	Category sql.NullString
+	FeedID   uuid.UUID
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, name string) ([]GetFeedFollowsForUserRow, error) {
@@ -99,7 +133,13 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, name string) ([]Get
 	var items []GetFeedFollowsForUserRow
 	for rows.Next() {
 		var i GetFeedFollowsForUserRow
-		if err := rows.Scan(&i.Feedname, &i.Username); err != nil {
+		if err := rows.Scan(
+			&i.Feedname,
+			&i.Username,
+			&i.Feedurl,
+			&i.Category,
+			&i.FeedID,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
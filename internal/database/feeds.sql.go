@@ -7,15 +7,56 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const claimNextFeedToFetch = `-- name: ClaimNextFeedToFetch :one
+UPDATE feeds SET last_fetched_at = $1
+WHERE id = (
+    SELECT id FROM feeds
+    WHERE (last_fetched_at IS NULL
+    OR last_fetched_at < $2)
+    AND (next_fetch_at IS NULL OR next_fetch_at <= $1)
+    ORDER BY last_fetched_at ASC NULLS FIRST
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, consecutive_failures, last_error, next_fetch_at, serial_id
+`
+
+type ClaimNextFeedToFetchParams struct {
+	ClaimedAt   sql.NullTime
+	StaleBefore sql.NullTime
+}
+
+func (q *Queries) ClaimNextFeedToFetch(ctx context.Context, arg ClaimNextFeedToFetchParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, claimNextFeedToFetch, arg.ClaimedAt, arg.StaleBefore)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.NextFetchAt,
+		&i.SerialID,
+	)
+	return i, err
+}
+
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
     VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, consecutive_failures, last_error, next_fetch_at, serial_id
 `
 
 type CreateFeedParams struct {
@@ -45,12 +86,18 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.NextFetchAt,
+		&i.SerialID,
 	)
 	return i, err
 }
 
 const getAllFeeds = `-- name: GetAllFeeds :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, consecutive_failures, last_error, next_fetch_at, serial_id FROM feeds
 `
 
 func (q *Queries) GetAllFeeds(ctx context.Context) ([]Feed, error) {
@@ -70,6 +117,12 @@ func (q *Queries) GetAllFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.NextFetchAt,
+			&i.SerialID,
 		); err != nil {
 			return nil, err
 		}
@@ -85,7 +138,7 @@ func (q *Queries) GetAllFeeds(ctx context.Context) ([]Feed, error) {
 }
 
 const getFeed = `-- name: GetFeed :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds WHERE feeds.url = $1
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, consecutive_failures, last_error, next_fetch_at, serial_id FROM feeds WHERE feeds.url = $1
 `
 
 func (q *Queries) GetFeed(ctx context.Context, url string) (Feed, error) {
@@ -99,18 +152,22 @@ func (q *Queries) GetFeed(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.NextFetchAt,
+		&i.SerialID,
 	)
 	return i, err
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
-ORDER BY feeds.last_fetched_at ASC NULLS FIRST
-LIMIT 1
+const getFeedFromID = `-- name: GetFeedFromID :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, consecutive_failures, last_error, next_fetch_at, serial_id FROM feeds WHERE id = $1
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
+func (q *Queries) GetFeedFromID(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedFromID, id)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -120,23 +177,77 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.NextFetchAt,
+		&i.SerialID,
+	)
+	return i, err
+}
+
+const markFeedFailed = `-- name: MarkFeedFailed :one
+UPDATE feeds SET updated_at = $1, consecutive_failures = consecutive_failures + 1,
+    last_error = $2, next_fetch_at = $3
+WHERE id = $4
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, consecutive_failures, last_error, next_fetch_at, serial_id
+`
+
+type MarkFeedFailedParams struct {
+	UpdatedAt   time.Time
+	LastError   sql.NullString
+	NextFetchAt sql.NullTime
+	ID          uuid.UUID
+}
+
+func (q *Queries) MarkFeedFailed(ctx context.Context, arg MarkFeedFailedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, markFeedFailed,
+		arg.UpdatedAt,
+		arg.LastError,
+		arg.NextFetchAt,
+		arg.ID,
+	)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.NextFetchAt,
+		&i.SerialID,
 	)
 	return i, err
 }
 
 const markFeedFetched = `-- name: MarkFeedFetched :one
-UPDATE feeds SET updated_at = $1, last_fetched_at = $1
-WHERE id = $2
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+UPDATE feeds SET updated_at = $1, last_fetched_at = $1, etag = $2, last_modified = $3,
+    consecutive_failures = 0, last_error = NULL, next_fetch_at = NULL
+WHERE id = $4
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, consecutive_failures, last_error, next_fetch_at, serial_id
 `
 
 type MarkFeedFetchedParams struct {
-	UpdatedAt time.Time
-	ID        uuid.UUID
+	UpdatedAt    time.Time
+	Etag         sql.NullString
+	LastModified sql.NullString
+	ID           uuid.UUID
 }
 
 func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, markFeedFetched, arg.UpdatedAt, arg.ID)
+	row := q.db.QueryRowContext(ctx, markFeedFetched,
+		arg.UpdatedAt,
+		arg.Etag,
+		arg.LastModified,
+		arg.ID,
+	)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -146,6 +257,43 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.NextFetchAt,
+		&i.SerialID,
 	)
 	return i, err
 }
+
+const resetFeedFetchState = `-- name: ResetFeedFetchState :exec
+UPDATE feeds SET last_fetched_at = NULL, etag = NULL, last_modified = NULL,
+    consecutive_failures = 0, last_error = NULL, next_fetch_at = NULL
+`
+
+func (q *Queries) ResetFeedFetchState(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, resetFeedFetchState)
+	return err
+}
+
+const transferFollowedFeeds = `-- name: TransferFollowedFeeds :exec
+UPDATE feeds SET user_id = (
+    SELECT feed_follows.user_id FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id
+    AND feed_follows.user_id <> $1
+    ORDER BY feed_follows.created_at
+    LIMIT 1
+)
+WHERE feeds.user_id = $1
+AND EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id
+    AND feed_follows.user_id <> $1
+)
+`
+
+func (q *Queries) TransferFollowedFeeds(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, transferFollowedFeeds, userID)
+	return err
+}
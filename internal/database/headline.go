@@ -0,0 +1,8 @@
+package database
+
+// Search snippets mark the matched terms with these control characters. They
+// cannot appear in XML feed text, so quotes such as « » in posts are kept.
+const (
+	HeadlineStart = "\x02"
+	HeadlineStop = "\x03"
+)
@@ -11,14 +11,29 @@ import (
 	"github.com/google/uuid"
 )
 
+type ApiToken struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+	TokenHash string
+	FeverKey  sql.NullString
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	ConsecutiveFailures int32
+	LastError           sql.NullString
+	NextFetchAt         sql.NullTime
+	SerialID            int64
 }
 
 type FeedFollow struct {
@@ -27,17 +42,53 @@ type FeedFollow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Category  sql.NullString
 }
 
 type Post struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       sql.NullString
-	Description sql.NullString
-	Url         sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Title        sql.NullString
+	Description  sql.NullString
+	Url          sql.NullString
+	PublishedAt  sql.NullTime
+	SearchVector interface{}
+	SerialID     int64
+	Author       sql.NullString
+	Categories   []string
+}
+
+type PostFeed struct {
+	PostID    uuid.UUID
+	FeedID    uuid.UUID
+	Guid      string
+	CreatedAt time.Time
+}
+
+type PostRead struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	ReadAt time.Time
+}
+
+type PostStar struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	StarredAt time.Time
+}
+
+type PublishToken struct {
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	TokenHash string
+}
+
+type Session struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	TokenHash string
 }
 
 type User struct {
@@ -46,3 +97,9 @@ type User struct {
 	UpdatedAt time.Time
 	Name      string
 }
+
+type UserPassword struct {
+	UserID       uuid.UUID
+	UpdatedAt    time.Time
+	PasswordHash string
+}
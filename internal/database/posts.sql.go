@@ -11,12 +11,38 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const adoptLegacyPostFeed = `-- name: AdoptLegacyPostFeed :one
+UPDATE post_feeds SET guid = $1
+WHERE feed_id = $2
+AND guid = 'legacy:' || $3::text
+RETURNING post_id, feed_id, guid, created_at
+`
+
+type AdoptLegacyPostFeedParams struct {
+	Guid   string
+	FeedID uuid.UUID
+	Url    string
+}
+
+func (q *Queries) AdoptLegacyPostFeed(ctx context.Context, arg AdoptLegacyPostFeedParams) (PostFeed, error) {
+	row := q.db.QueryRowContext(ctx, adoptLegacyPostFeed, arg.Guid, arg.FeedID, arg.Url)
+	var i PostFeed
+	err := row.Scan(
+		&i.PostID,
+		&i.FeedID,
+		&i.Guid,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, created_at, updated_at, title, description, url, published_at, feed_id
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, author, categories)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, created_at, updated_at, title, description, url, published_at, search_vector, serial_id, author, categories
 `
 
 type CreatePostParams struct {
@@ -27,7 +53,8 @@ type CreatePostParams struct {
 	Url         sql.NullString
 	Description sql.NullString
 	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+	Author      sql.NullString
+	Categories  []string
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -39,7 +66,8 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Url,
 		arg.Description,
 		arg.PublishedAt,
-		arg.FeedID,
+		arg.Author,
+		pq.Array(arg.Categories),
 	)
 	var i Post
 	err := row.Scan(
@@ -50,33 +78,224 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.Url,
 		&i.PublishedAt,
+		&i.SearchVector,
+		&i.SerialID,
+		&i.Author,
+		pq.Array(&i.Categories),
+	)
+	return i, err
+}
+
+const createPostFeed = `-- name: CreatePostFeed :one
+INSERT INTO post_feeds (post_id, feed_id, guid, created_at)
+VALUES ($1, $2, $3, $4)
+RETURNING post_id, feed_id, guid, created_at
+`
+
+type CreatePostFeedParams struct {
+	PostID    uuid.UUID
+	FeedID    uuid.UUID
+	Guid      string
+	CreatedAt time.Time
+}
+
+func (q *Queries) CreatePostFeed(ctx context.Context, arg CreatePostFeedParams) (PostFeed, error) {
+	row := q.db.QueryRowContext(ctx, createPostFeed,
+		arg.PostID,
+		arg.FeedID,
+		arg.Guid,
+		arg.CreatedAt,
+	)
+	var i PostFeed
+	err := row.Scan(
+		&i.PostID,
 		&i.FeedID,
+		&i.Guid,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteOrphanedPosts = `-- name: DeleteOrphanedPosts :exec
+DELETE FROM posts
+WHERE NOT EXISTS (
+    SELECT 1 FROM post_feeds
+    WHERE post_feeds.post_id = posts.id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_stars
+    WHERE post_stars.post_id = posts.id
+)
+`
+
+func (q *Queries) DeleteOrphanedPosts(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteOrphanedPosts)
+	return err
+}
+
+const deleteUnstarredPosts = `-- name: DeleteUnstarredPosts :exec
+DELETE FROM posts
+WHERE NOT EXISTS (
+    SELECT 1 FROM post_stars
+    WHERE post_stars.post_id = posts.id
+)
+`
+
+func (q *Queries) DeleteUnstarredPosts(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteUnstarredPosts)
+	return err
+}
+
+const getPost = `-- name: GetPost :one
+SELECT id, created_at, updated_at, title, description, url, published_at, search_vector, serial_id, author, categories FROM posts WHERE id = $1
+`
+
+func (q *Queries) GetPost(ctx context.Context, id uuid.UUID) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPost, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Description,
+		&i.Url,
+		&i.PublishedAt,
+		&i.SearchVector,
+		&i.SerialID,
+		&i.Author,
+		pq.Array(&i.Categories),
+	)
+	return i, err
+}
+
+const getPostByURL = `-- name: GetPostByURL :one
+SELECT id, created_at, updated_at, title, description, url, published_at, search_vector, serial_id, author, categories FROM posts WHERE url = $1
+LIMIT 1
+`
+
+func (q *Queries) GetPostByURL(ctx context.Context, url sql.NullString) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByURL, url)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Description,
+		&i.Url,
+		&i.PublishedAt,
+		&i.SearchVector,
+		&i.SerialID,
+		&i.Author,
+		pq.Array(&i.Categories),
+	)
+	return i, err
+}
+
+const getPostByURLNotInFeed = `-- name: GetPostByURLNotInFeed :one
+SELECT id, created_at, updated_at, title, description, url, published_at, search_vector, serial_id, author, categories FROM posts
+WHERE url = $1
+AND NOT EXISTS (
+    SELECT 1 FROM post_feeds
+    WHERE post_feeds.post_id = posts.id
+    AND post_feeds.feed_id = $2
+)
+LIMIT 1
+`
+
+type GetPostByURLNotInFeedParams struct {
+	Url    sql.NullString
+	FeedID uuid.UUID
+}
+
+func (q *Queries) GetPostByURLNotInFeed(ctx context.Context, arg GetPostByURLNotInFeedParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByURLNotInFeed, arg.Url, arg.FeedID)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Description,
+		&i.Url,
+		&i.PublishedAt,
+		&i.SearchVector,
+		&i.SerialID,
+		&i.Author,
+		pq.Array(&i.Categories),
 	)
 	return i, err
 }
 
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.description, posts.url, posts.published_at, posts.feed_id FROM posts
-JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
-WHERE feed_follows.user_id = $1
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.description, posts.url, posts.published_at, posts.search_vector, posts.serial_id, posts.author, posts.categories, EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id
+    AND post_reads.user_id = $1
+) AS is_read
+FROM posts
+WHERE EXISTS (
+    SELECT 1 FROM post_feeds
+    JOIN feed_follows ON feed_follows.feed_id = post_feeds.feed_id
+    WHERE post_feeds.post_id = posts.id
+    AND feed_follows.user_id = $1
+    AND ($2::uuid IS NULL OR post_feeds.feed_id = $2)
+    AND ($3::text IS NULL OR feed_follows.category = $3)
+)
+AND ($4::boolean OR NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id
+    AND post_reads.user_id = $1
+))
+AND ($5::text IS NULL OR posts.search_vector @@ websearch_to_tsquery('english', $5))
 ORDER BY posts.published_at DESC NULLS LAST
-LIMIT $2
+LIMIT $6
+OFFSET $7
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID      uuid.UUID
+	FeedID      uuid.NullUUID
+	Category    sql.NullString
+	IncludeRead bool
+	Search      sql.NullString
+	PostLimit   int32
+	PostOffset  int32
+}
+
+type GetPostsForUserRow struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Title        sql.NullString
+	Description  sql.NullString
+	Url          sql.NullString
+	PublishedAt  sql.NullTime
+	SearchVector interface{}
+	SerialID     int64
+	Author       sql.NullString
+	Categories   []string
+	IsRead       bool
 }
 
-func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.FeedID,
+		arg.Category,
+		arg.IncludeRead,
+		arg.Search,
+		arg.PostLimit,
+		arg.PostOffset,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Post
+	var items []GetPostsForUserRow
 	for rows.Next() {
-		var i Post
+		var i GetPostsForUserRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
@@ -85,7 +304,137 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.Url,
 			&i.PublishedAt,
-			&i.FeedID,
+			&i.SearchVector,
+			&i.SerialID,
+			&i.Author,
+			pq.Array(&i.Categories),
+			&i.IsRead,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockPostURL = `-- name: LockPostURL :exec
+SELECT pg_advisory_xact_lock(hashtextextended($1::text, 0))
+`
+
+func (q *Queries) LockPostURL(ctx context.Context, url string) error {
+	_, err := q.db.ExecContext(ctx, lockPostURL, url)
+	return err
+}
+
+const postFeedExists = `-- name: PostFeedExists :one
+SELECT EXISTS (
+    SELECT 1 FROM post_feeds
+    WHERE feed_id = $1
+    AND guid = $2
+)
+`
+
+type PostFeedExistsParams struct {
+	FeedID uuid.UUID
+	Guid   string
+}
+
+func (q *Queries) PostFeedExists(ctx context.Context, arg PostFeedExistsParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, postFeedExists, arg.FeedID, arg.Guid)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const postInUserFeeds = `-- name: PostInUserFeeds :one
+SELECT EXISTS (
+    SELECT 1 FROM post_feeds
+    JOIN feed_follows ON feed_follows.feed_id = post_feeds.feed_id
+    WHERE post_feeds.post_id = $1
+    AND feed_follows.user_id = $2
+)
+`
+
+type PostInUserFeedsParams struct {
+	PostID uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) PostInUserFeeds(ctx context.Context, arg PostInUserFeedsParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, postInUserFeeds, arg.PostID, arg.UserID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const searchPostsForUser = `-- name: SearchPostsForUser :many
+SELECT posts.id, posts.title, posts.url, posts.published_at,
+    ts_rank(posts.search_vector, query)::real AS rank,
+    ts_headline('english', coalesce(posts.description, posts.title, ''), query,
+        'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxFragments=2, MaxWords=20, MinWords=8')::text AS snippet
+FROM posts
+CROSS JOIN websearch_to_tsquery('english', $1) AS query
+WHERE posts.search_vector @@ query
+AND EXISTS (
+    SELECT 1 FROM post_feeds
+    JOIN feed_follows ON feed_follows.feed_id = post_feeds.feed_id
+    WHERE post_feeds.post_id = posts.id
+    AND feed_follows.user_id = $2
+    AND ($3::uuid IS NULL OR post_feeds.feed_id = $3)
+)
+AND ($4::timestamp IS NULL OR posts.published_at >= $4)
+AND ($5::timestamp IS NULL OR posts.published_at < $5)
+ORDER BY rank DESC, posts.published_at DESC NULLS LAST
+LIMIT $6
+`
+
+type SearchPostsForUserParams struct {
+	Query     string
+	UserID    uuid.UUID
+	FeedID    uuid.NullUUID
+	Since     sql.NullTime
+	Until     sql.NullTime
+	PostLimit int32
+}
+
+type SearchPostsForUserRow struct {
+	ID          uuid.UUID
+	Title       sql.NullString
+	Url         sql.NullString
+	PublishedAt sql.NullTime
+	Rank        float32
+	Snippet     string
+}
+
+func (q *Queries) SearchPostsForUser(ctx context.Context, arg SearchPostsForUserParams) ([]SearchPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPostsForUser,
+		arg.Query,
+		arg.UserID,
+		arg.FeedID,
+		arg.Since,
+		arg.Until,
+		arg.PostLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPostsForUserRow
+	for rows.Next() {
+		var i SearchPostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.PublishedAt,
+			&i.Rank,
+			&i.Snippet,
 		); err != nil {
 			return nil, err
 		}
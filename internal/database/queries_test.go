@@ -0,0 +1,359 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pressly/goose/v3"
+)
+
+// generatedQueries returns the query constants of the generated code keyed
+// by name.
+func generatedQueries(t *testing.T) map[string]string {
+	t.Helper()
+	fset := token.NewFileSet()
+	byName := map[string]string{}
+	files, err := os.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range files {
+		if !strings.HasSuffix(entry.Name(), ".sql.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, entry.Name(), nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(file, func(node ast.Node) bool {
+			lit, ok := node.(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			query, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name, ok := queryName(query); ok {
+				byName[name] = query
+			}
+			return true
+		})
+	}
+	return byName
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func querierMethods() []string {
+	querier := reflect.TypeOf((*Querier)(nil)).Elem()
+	names := make([]string, 0, querier.NumMethod())
+	for i := 0; i < querier.NumMethod(); i++ {
+		names = append(names, querier.Method(i).Name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// TestGeneratedCodeMatchesQueries checks that the query files and the code
+// generated from them name the same queries, so that a query removed from
+// one is removed from the other.
+func TestGeneratedCodeMatchesQueries(t *testing.T) {
+	files, err := readQueries(os.DirFS("../../sql/queries"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileNames := sortedKeys(files)
+	generatedNames := sortedKeys(generatedQueries(t))
+	if !slices.Equal(fileNames, generatedNames) {
+		t.Errorf("sql/queries names %v\ngenerated code names %v", fileNames, generatedNames)
+	}
+	if methods := querierMethods(); !slices.Equal(generatedNames, methods) {
+		t.Errorf("generated code names %v\nQuerier methods %v", generatedNames, methods)
+	}
+}
+
+// maxParam returns the highest numbered parameter in a query, written $N
+// for Postgres and ?N for SQLite.
+func maxParam(query string, prefix byte) int {
+	highest := 0
+	for i := 0; i < len(query); i++ {
+		if query[i] != prefix {
+			continue
+		}
+		j := i + 1
+		for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+			j++
+		}
+		if n, err := strconv.Atoi(query[i+1 : j]); err == nil && n > highest {
+			highest = n
+		}
+	}
+	return highest
+}
+
+// TestSQLiteQueriesMatchGenerated checks that every generated query has a
+// SQLite twin taking the same number of parameters. The SQLite queries are
+// bound by position, so a twin with a parameter more or less would read the
+// wrong arguments.
+func TestSQLiteQueriesMatchGenerated(t *testing.T) {
+	sqliteQueries, err := readQueries(os.DirFS("../../sql/sqlite/queries"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated := generatedQueries(t)
+	if sqliteNames, generatedNames := sortedKeys(sqliteQueries), sortedKeys(generated); !slices.Equal(sqliteNames, generatedNames) {
+		t.Errorf("sql/sqlite/queries names %v\ngenerated code names %v", sqliteNames, generatedNames)
+	}
+	for name, query := range generated {
+		sqliteQuery, ok := sqliteQueries[name]
+		if !ok {
+			continue
+		}
+		if want, got := maxParam(query, '$'), maxParam(sqliteQuery, '?'); want != got {
+			t.Errorf("%s takes %d parameters on Postgres but %d on SQLite", name, want, got)
+		}
+	}
+}
+
+// TestOpenSQLiteChecksQueries checks that a database cannot be opened with a
+// query missing or misnamed in the SQLite files.
+func TestOpenSQLiteChecksQueries(t *testing.T) {
+	files := fstest.MapFS{}
+	paths, err := filepath.Glob("../../sql/sqlite/queries/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[filepath.Base(path)] = &fstest.MapFile{Data: data}
+	}
+	users := string(files["users.sql"].Data)
+	path := filepath.Join(t.TempDir(), "gator.db")
+
+	tests := []struct {
+		name	string
+		users	string
+	}{
+		{name: "missing", users: strings.Replace(users, "-- name: GetUser :one", "", 1)},
+		{name: "misnamed", users: strings.Replace(users, "-- name: GetUser :one", "-- name: GetUsr :one", 1)},
+	}
+	for _, test := range tests {
+		queries := maps.Clone(files)
+		queries["users.sql"] = &fstest.MapFile{Data: []byte(test.users)}
+		db, err := OpenSQLite(path, queries)
+		if err == nil {
+			db.Close()
+			t.Errorf("%s: opened with the GetUser query %s", test.name, test.name)
+		} else if !strings.Contains(err.Error(), "GetUs") {
+			t.Errorf("%s: error %q does not name the query", test.name, err)
+		}
+	}
+}
+
+// openSQLite opens a migrated SQLite database that is removed after the test
+func openSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "gator.db"), os.DirFS("../../sql/sqlite/queries"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	err = goose.SetDialect("sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	goose.SetLogger(goose.NopLogger())
+	err = goose.Up(db, "../../sql/sqlite/schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// setupQueries run first in TestSQLiteQuerier, in order, so that the reads
+// after them find rows to scan.
+var setupQueries = []string{
+	"CreateUser",
+	"CreateFeed",
+	"CreateFeedFollows",
+	"CreatePost",
+	"CreatePostFeed",
+	"CreateAPIToken",
+	"CreateSession",
+	"SetPublishToken",
+	"SetUserPassword",
+	"StarPost",
+	"MarkPostRead",
+}
+
+// sampleValue returns a value of type typ for a query argument. Strings and
+// numbers are set so that checks on them pass, and every number is 1 to
+// match the first serial ID. UUIDs are left nil, so every row refers to every
+// other, and nullable values other than strings are left NULL so that the
+// filters they drive are off.
+func sampleValue(typ reflect.Type) reflect.Value {
+	value := reflect.New(typ).Elem()
+	switch typ {
+	case reflect.TypeOf(time.Time{}):
+		value.Set(reflect.ValueOf(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)))
+		return value
+	case reflect.TypeOf(uuid.UUID{}):
+		return value
+	case reflect.TypeOf(sql.NullString{}):
+		value.Set(reflect.ValueOf(sql.NullString{String: "gator", Valid: true}))
+		return value
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		value.SetString("gator")
+	case reflect.Bool:
+		value.SetBool(true)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		value.SetInt(1)
+	case reflect.Slice:
+		value.Set(reflect.Append(value, sampleValue(typ.Elem())))
+	case reflect.Struct:
+		if _, nullable := typ.FieldByName("Valid"); nullable {
+			break
+		}
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			// Offsets and exclusions would skip the one sample row
+			if strings.HasSuffix(field.Name, "Offset") || strings.HasPrefix(field.Name, "Exclude") {
+				continue
+			}
+			value.Field(i).Set(sampleValue(field.Type))
+		}
+	}
+	return value
+}
+
+// TestSQLiteQuerier runs every Querier method against a migrated SQLite
+// database to catch queries the driver rejects or whose columns do not scan
+// into the generated rows. Foreign keys are off so that the nil UUIDs of
+// the sample arguments need no parent rows.
+func TestSQLiteQuerier(t *testing.T) {
+	db := openSQLite(t)
+	_, err := db.Exec("PRAGMA foreign_keys = OFF")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Deletes go last so the reads before them see the setup rows
+	order := slices.Clone(setupQueries)
+	var deletes []string
+	for _, name := range querierMethods() {
+		if slices.Contains(setupQueries, name) {
+			continue
+		}
+		if strings.HasPrefix(name, "Delete") {
+			deletes = append(deletes, name)
+		} else {
+			order = append(order, name)
+		}
+	}
+	order = append(order, deletes...)
+
+	queries := reflect.ValueOf(Querier(New(db)))
+	ctx := reflect.ValueOf(context.Background())
+	for _, name := range order {
+		method := queries.MethodByName(name)
+		args := []reflect.Value{ctx}
+		for i := 1; i < method.Type().NumIn(); i++ {
+			args = append(args, sampleValue(method.Type().In(i)))
+		}
+		results := method.Call(args)
+		err, _ := results[len(results)-1].Interface().(error)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		// Lists must find the sample row, or their columns went unscanned.
+		// The sample post was marked read, so it is never listed as unread.
+		rows := results[0]
+		listed := rows.Kind() != reflect.Slice || rows.Len() > 0
+		if !listed && !slices.Contains(deletes, name) && name != "GetUnreadPostSerialIDsForUser" {
+			t.Errorf("%s returned no rows", name)
+		}
+	}
+}
+
+// TestSQLiteSerialIDsNotReused checks that feeds and posts added after the
+// newest ones were deleted get new serial ids. Sync clients keep the ids they
+// have seen, so a reused id would point them at the wrong feed or post.
+func TestSQLiteSerialIDsNotReused(t *testing.T) {
+	ctx := context.Background()
+	queries := New(openSQLite(t))
+	now := time.Now().UTC()
+	addFeedAndPost := func() (Feed, Post) {
+		t.Helper()
+		user, err := queries.CreateUser(ctx, CreateUserParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: "gator"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		feed, err := queries.CreateFeed(ctx, CreateFeedParams{
+			ID: uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			Name: "Feed",
+			Url: "https://example.com/feed",
+			UserID: user.ID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		post, err := queries.CreatePost(ctx, CreatePostParams{
+			ID: uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			Title: sql.NullString{String: "Post", Valid: true},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return feed, post
+	}
+
+	feed, post := addFeedAndPost()
+	err := queries.DeleteAllUsers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = queries.DeleteOrphanedPosts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newFeed, newPost := addFeedAndPost()
+	if newFeed.SerialID <= feed.SerialID {
+		t.Errorf("new feed has serial id %d after deleted feed %d", newFeed.SerialID, feed.SerialID)
+	}
+	if newPost.SerialID <= post.SerialID {
+		t.Errorf("new post has serial id %d after deleted post %d", newPost.SerialID, post.SerialID)
+	}
+}
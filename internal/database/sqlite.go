@@ -0,0 +1,203 @@
+package database
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteParams are added to every SQLite DSN. Foreign keys are off by
+// default in SQLite, and times are written in a format that sorts by value.
+const sqliteParams = "_pragma=foreign_keys(1)&_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_time_format=sqlite"
+
+// OpenSQLite opens a SQLite database for the generated queries. The queries
+// are generated from the Postgres query files only, so each one is swapped on
+// the way to the driver for the query of the same name in the SQLite query
+// files. The arguments are passed through as the generated code built them:
+// a SQLite query reads them by position as ?N, and array arguments arrive as
+// lib/pq array literals. A query missing from the SQLite files fails the
+// open, and queries_test.go checks that both sets of queries stay in step.
+func OpenSQLite(path string, queries fs.FS) (*sql.DB, error) {
+	byName, err := readQueries(queries)
+	if err != nil {
+		return nil, err
+	}
+	err = checkQueries(byName)
+	if err != nil {
+		return nil, err
+	}
+	dsn := path
+	if strings.Contains(dsn, "?") {
+		dsn += "&" + sqliteParams
+	} else {
+		dsn += "?" + sqliteParams
+	}
+	db := sql.OpenDB(&sqliteConnector{dsn: dsn, queries: byName})
+	// SQLite allows one writer at a time. A single connection keeps
+	// concurrent writers queued in Go instead of failing as busy.
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// readQueries splits sqlc query files into queries keyed by name
+func readQueries(queries fs.FS) (map[string]string, error) {
+	files, err := fs.Glob(queries, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string]string{}
+	for _, file := range files {
+		data, err := fs.ReadFile(queries, file)
+		if err != nil {
+			return nil, err
+		}
+		var name string
+		var query strings.Builder
+		save := func() {
+			if name != "" {
+				byName[name] = strings.TrimSuffix(strings.TrimSpace(query.String()), ";")
+			}
+		}
+		scanner := bufio.NewScanner(strings.NewReader(string(data)))
+		for scanner.Scan() {
+			line := scanner.Text()
+			if next, ok := queryName(line); ok {
+				save()
+				name = next
+				query.Reset()
+			}
+			query.WriteString(line)
+			query.WriteString("\n")
+		}
+		save()
+	}
+	return byName, nil
+}
+
+// checkQueries makes sure that the SQLite queries match the Querier methods
+// one for one, so that a missing or misnamed query is found on start up
+// rather than when it first runs.
+func checkQueries(byName map[string]string) error {
+	querier := reflect.TypeOf((*Querier)(nil)).Elem()
+	var missing []string
+	for i := 0; i < querier.NumMethod(); i++ {
+		name := querier.Method(i).Name
+		if _, ok := byName[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("no SQLite query for %s", strings.Join(missing, ", "))
+	}
+	for name := range byName {
+		if _, ok := querier.MethodByName(name); !ok {
+			return fmt.Errorf("SQLite query %s has no generated method", name)
+		}
+	}
+	return nil
+}
+
+// queryName reads the name from the "-- name: Name :kind" line sqlc puts at
+// the start of each query.
+func queryName(query string) (string, bool) {
+	rest, ok := strings.CutPrefix(query, "-- name: ")
+	if !ok {
+		return "", false
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", false
+	}
+	return fields[0], true
+}
+
+type sqliteConnector struct {
+	dsn		string
+	queries	map[string]string
+}
+
+func (c *sqliteConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Driver().Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteConn{Conn: conn, queries: c.queries}, nil
+}
+
+// sqliteDriver is the driver modernc.org/sqlite registers. Functions
+// registered with the package, such as websearch_to_fts5, are only added to
+// its connections.
+var sqliteDriver = func() driver.Driver {
+	db, err := sql.Open("sqlite", "")
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+	return db.Driver()
+}()
+
+func (c *sqliteConnector) Driver() driver.Driver {
+	return sqliteDriver
+}
+
+// sqliteConn rewrites named queries before passing them to the SQLite
+// connection. Other statements, such as goose's, pass through unchanged.
+type sqliteConn struct {
+	driver.Conn
+	queries	map[string]string
+}
+
+func (c *sqliteConn) rewrite(query string) (string, error) {
+	name, ok := queryName(query)
+	if !ok {
+		return query, nil
+	}
+	rewritten, ok := c.queries[name]
+	if !ok {
+		return "", fmt.Errorf("query %s has no SQLite version", name)
+	}
+	return rewritten, nil
+}
+
+func (c *sqliteConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *sqliteConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	query, err := c.rewrite(query)
+	if err != nil {
+		return nil, err
+	}
+	return c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+}
+
+func (c *sqliteConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c *sqliteConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	query, err := c.rewrite(query)
+	if err != nil {
+		return nil, err
+	}
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c *sqliteConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	query, err := c.rewrite(query)
+	if err != nil {
+		return nil, err
+	}
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+func (c *sqliteConn) ResetSession(ctx context.Context) error {
+	return c.Conn.(driver.SessionResetter).ResetSession(ctx)
+}
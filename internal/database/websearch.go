@@ -0,0 +1,124 @@
+package database
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"modernc.org/sqlite"
+)
+
+// matchNothing is an FTS5 query no row matches, used for searches that only
+// exclude words.
+const matchNothing = `""`
+
+func init() {
+	// SQLite queries search with websearch_to_fts5(query), as the Postgres
+	// ones do with websearch_to_tsquery, so both take the same syntax.
+	sqlite.MustRegisterDeterministicScalarFunction("websearch_to_fts5", 1, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		switch query := args[0].(type) {
+		case nil:
+			return nil, nil
+		case string:
+			return webSearchToFTS5(query), nil
+		case []byte:
+			return webSearchToFTS5(string(query)), nil
+		default:
+			return nil, fmt.Errorf("websearch_to_fts5: unsupported argument %T", query)
+		}
+	})
+}
+
+// SearchGroup is a run of terms a match must all satisfy. Groups are
+// separated by OR.
+type SearchGroup struct {
+	Include	[]string
+	Exclude	[]string
+}
+
+// ParseWebSearch splits a web-style search, the syntax of Postgres's
+// websearch_to_tsquery, into its alternatives. Words and "quoted phrases"
+// must all match, a leading - excludes a word or phrase, and OR separates
+// alternatives.
+func ParseWebSearch(query string) []SearchGroup {
+	groups := []SearchGroup{{}}
+	rest := []rune(query)
+	for {
+		for len(rest) > 0 && unicode.IsSpace(rest[0]) {
+			rest = rest[1:]
+		}
+		if len(rest) == 0 {
+			break
+		}
+		negate := false
+		if rest[0] == '-' {
+			negate = true
+			rest = rest[1:]
+		}
+		var term string
+		quoted := len(rest) > 0 && rest[0] == '"'
+		if quoted {
+			end := 1
+			for end < len(rest) && rest[end] != '"' {
+				end++
+			}
+			term = string(rest[1:end])
+			rest = rest[min(end+1, len(rest)):]
+		} else {
+			end := 0
+			for end < len(rest) && !unicode.IsSpace(rest[end]) {
+				end++
+			}
+			term = string(rest[:end])
+			rest = rest[end:]
+		}
+		if !quoted && !negate && strings.EqualFold(term, "or") {
+			groups = append(groups, SearchGroup{})
+			continue
+		}
+		if strings.TrimSpace(term) == "" {
+			continue
+		}
+		group := &groups[len(groups)-1]
+		if negate {
+			group.Exclude = append(group.Exclude, term)
+		} else {
+			group.Include = append(group.Include, term)
+		}
+	}
+	return groups
+}
+
+// webSearchToFTS5 translates a web-style search into an FTS5 query. Every
+// term is quoted, so characters FTS5 reads as syntax are searched for as
+// text. FTS5 cannot match on exclusions alone, so an alternative without a
+// word to match is dropped, and a search made only of exclusions matches
+// nothing.
+func webSearchToFTS5(query string) string {
+	var alternatives []string
+	for _, group := range ParseWebSearch(query) {
+		if len(group.Include) == 0 {
+			continue
+		}
+		var terms []string
+		for _, term := range group.Include {
+			terms = append(terms, quoteFTS5(term))
+		}
+		alternative := strings.Join(terms, " ")
+		for _, term := range group.Exclude {
+			alternative += " NOT " + quoteFTS5(term)
+		}
+		alternatives = append(alternatives, "("+alternative+")")
+	}
+	if len(alternatives) == 0 {
+		return matchNothing
+	}
+	return strings.Join(alternatives, " OR ")
+}
+
+// quoteFTS5 quotes a term as an FTS5 string, which matches its words as a
+// phrase.
+func quoteFTS5(term string) string {
+	return `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestWebSearchToFTS5(t *testing.T) {
+	tests := []struct {
+		search	string
+		want	string
+	}{
+		{"golang", `("golang")`},
+		{"golang rust", `("golang" "rust")`},
+		{"c++", `("c++")`},
+		{"golang -rust", `("golang" NOT "rust")`},
+		{"golang -rust -zig", `("golang" NOT "rust" NOT "zig")`},
+		{"golang or rust", `("golang") OR ("rust")`},
+		{"golang OR rust -zig", `("golang") OR ("rust" NOT "zig")`},
+		{`"rob pike" go`, `("rob pike" "go")`},
+		{`go -"rob pike"`, `("go" NOT "rob pike")`},
+		{`say "hi`, `("say" "hi")`},
+		{`a"b`, `("a""b")`},
+		{"NOT AND near(", `("NOT" "AND" "near(")`},
+		{"-rust", `""`},
+		{"-rust or golang", `("golang")`},
+		{"  ", `""`},
+		{"or", `""`},
+	}
+	for _, test := range tests {
+		if got := webSearchToFTS5(test.search); got != test.want {
+			t.Errorf("webSearchToFTS5(%q) = %s, want %s", test.search, got, test.want)
+		}
+	}
+}
+
+// TestSQLiteSearch checks that web-style searches, which FTS5 would reject
+// or misread, run on SQLite and find the posts Postgres would.
+func TestSQLiteSearch(t *testing.T) {
+	ctx := context.Background()
+	queries := New(openSQLite(t))
+	now := time.Now().UTC()
+	user, err := queries.CreateUser(ctx, CreateUserParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: "gator"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := queries.CreateFeed(ctx, CreateFeedParams{
+		ID: uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Name: "Feed",
+		Url: "https://example.com/feed",
+		UserID: user.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = queries.CreateFeedFollows(ctx, CreateFeedFollowsParams{
+		ID: uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID: user.ID,
+		FeedID: feed.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	titles := []string{"Learning golang and c++", "Golang versus rust", "Rust in production", "Les «guillemets» français"}
+	for _, title := range titles {
+		post, err := queries.CreatePost(ctx, CreatePostParams{
+			ID: uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			Title: sql.NullString{String: title, Valid: true},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = queries.CreatePostFeed(ctx, CreatePostFeedParams{PostID: post.ID, FeedID: feed.ID, Guid: title, CreatedAt: now})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		search	string
+		want	[]string
+	}{
+		{"c++", []string{"Learning golang and c++"}},
+		{"golang -rust", []string{"Learning golang and c++"}},
+		{"production or c++", []string{"Learning golang and c++", "Rust in production"}},
+		{`"versus rust"`, []string{"Golang versus rust"}},
+		{"-golang", nil},
+	}
+	for _, test := range tests {
+		rows, err := queries.SearchPostsForUser(ctx, SearchPostsForUserParams{Query: test.search, UserID: user.ID, PostLimit: 10})
+		if err != nil {
+			t.Errorf("search %q: %v", test.search, err)
+			continue
+		}
+		var found []string
+		for _, row := range rows {
+			found = append(found, row.Title.String)
+		}
+		slices.Sort(found)
+		if !slices.Equal(found, test.want) {
+			t.Errorf("search %q found %q, want %q", test.search, found, test.want)
+		}
+
+		posts, err := queries.GetPostsForUser(ctx, GetPostsForUserParams{
+			UserID: user.ID,
+			IncludeRead: true,
+			Search: sql.NullString{String: test.search, Valid: true},
+			PostLimit: 10,
+		})
+		if err != nil {
+			t.Errorf("posts matching %q: %v", test.search, err)
+			continue
+		}
+		found = nil
+		for _, post := range posts {
+			found = append(found, post.Title.String)
+		}
+		slices.Sort(found)
+		if !slices.Equal(found, test.want) {
+			t.Errorf("posts matching %q are %q, want %q", test.search, found, test.want)
+		}
+	}
+
+	// Matches are marked with control characters, so quotes in the text stay
+	rows, err := queries.SearchPostsForUser(ctx, SearchPostsForUserParams{Query: "guillemets", UserID: user.ID, PostLimit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Les «" + HeadlineStart + "guillemets" + HeadlineStop + "» français"
+	if len(rows) != 1 {
+		t.Fatalf("search found %d posts, want 1", len(rows))
+	}
+	if rows[0].Snippet != want {
+		t.Errorf("snippet is %q, want %q", rows[0].Snippet, want)
+	}
+}
@@ -0,0 +1,111 @@
+package feed
+
+import (
+	"strings"
+)
+
+type AtomFeed struct {
+	Title		AtomText	`xml:"title"`
+	Subtitle	AtomText	`xml:"subtitle"`
+	Link		[]AtomLink	`xml:"link"`
+	Entry		[]AtomEntry	`xml:"entry"`
+}
+
+type AtomEntry struct {
+	ID			string			`xml:"id"`
+	Title		AtomText		`xml:"title"`
+	Link		[]AtomLink		`xml:"link"`
+	Summary		AtomText		`xml:"summary"`
+	Content		AtomText		`xml:"content"`
+	Published	string			`xml:"published"`
+	Updated		string			`xml:"updated"`
+	Author		[]AtomPerson	`xml:"author"`
+	Category	[]AtomCategory	`xml:"category"`
+}
+
+type AtomPerson struct {
+	Name	string	`xml:"name"`
+}
+
+type AtomCategory struct {
+	Term	string	`xml:"term,attr"`
+	Label	string	`xml:"label,attr"`
+}
+
+type AtomLink struct {
+	Href	string	`xml:"href,attr"`
+	Rel		string	`xml:"rel,attr"`
+	Type	string	`xml:"type,attr"`
+}
+
+// AtomText is an Atom text construct. Text and html content is carried as
+// character data, xhtml content is carried as child elements.
+type AtomText struct {
+	Type	string	`xml:"type,attr"`
+	Text	string	`xml:",chardata"`
+	Inner	string	`xml:",innerxml"`
+}
+
+// String returns the value of the text construct
+func (t AtomText) String() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.Inner)
+	}
+	return strings.TrimSpace(t.Text)
+}
+
+// alternateLink picks the link that points at the html version of the
+// resource. Links without a rel are alternate links per RFC 4287.
+func alternateLink(links []AtomLink) string {
+	for _, l := range links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			return l.Href
+		}
+	}
+	for _, l := range links {
+		if l.Rel != "self" && l.Rel != "enclosure" {
+			return l.Href
+		}
+	}
+	if len(links) > 0 {
+		return links[0].Href
+	}
+	return ""
+}
+
+// toRSSFeed normalizes an Atom feed into the RSSFeed item model
+func (a *AtomFeed) toRSSFeed() *RSSFeed {
+	feed := new(RSSFeed)
+	feed.Channel.Title = a.Title.String()
+	feed.Channel.Link = alternateLink(a.Link)
+	feed.Channel.Description = a.Subtitle.String()
+	for _, entry := range a.Entry {
+		item := RSSItem{
+			Title: entry.Title.String(),
+			Link: alternateLink(entry.Link),
+			Description: entry.Summary.String(),
+			PubDate: strings.TrimSpace(entry.Published),
+			GUID: strings.TrimSpace(entry.ID),
+		}
+		if item.Description == "" {
+			item.Description = entry.Content.String()
+		}
+		if item.PubDate == "" {
+			item.PubDate = strings.TrimSpace(entry.Updated)
+		}
+		var authors []string
+		for _, author := range entry.Author {
+			authors = append(authors, author.Name)
+		}
+		item.Author = strings.Join(uniqueStrings(authors), ", ")
+		for _, category := range entry.Category {
+			if category.Term == "" {
+				category.Term = category.Label
+			}
+			item.Category = append(item.Category, category.Term)
+		}
+		item.Category = uniqueStrings(item.Category)
+		feed.Channel.Item = append(feed.Channel.Item, item)
+	}
+	return feed
+}
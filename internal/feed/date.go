@@ -0,0 +1,179 @@
+package feed
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// dateLayouts are tried in order after the weekday has been stripped and any
+// timezone abbreviation has been replaced with a numeric offset.
+var dateLayouts = []string{
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"2 Jan 2006 15:04:05 -07:00",
+	"2 January 2006 15:04:05 -0700",
+	"2 January 2006 15:04 -0700",
+	"2-Jan-06 15:04:05 -0700",
+	"2-Jan-2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006 15:04",
+	"2 Jan 2006",
+	"2 January 2006",
+	"Jan 2, 2006 15:04:05 -0700",
+	"Jan 2, 2006",
+	"January 2, 2006",
+	"January 2, 2006 15:04:05 -0700",
+	"January 2, 2006 15:04 -0700",
+	"January 2, 2006 15:04:05",
+	"January 2, 2006 15:04",
+	"January 2, 2006 3:04:05 PM -0700",
+	"January 2, 2006 3:04 PM -0700",
+	"January 2, 2006 3:04:05 PM",
+	"January 2, 2006 3:04 PM",
+	"Jan 2, 2006 3:04:05 PM -0700",
+	"Jan 2, 2006 3:04 PM -0700",
+	"Jan 2, 2006 3:04:05 PM",
+	"Jan 2, 2006 3:04 PM",
+	"2 Jan 2006 3:04:05 PM -0700",
+	"2 Jan 2006 3:04 PM -0700",
+	"2 Jan 2006 3:04:05 PM",
+	"2 Jan 2006 3:04 PM",
+	"2 January 2006 3:04 PM",
+	"01/02/2006 3:04:05 PM",
+	"01/02/2006 3:04 PM",
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04-0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 -07:00",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"Mon Jan _2 15:04:05 2006",
+	"Mon Jan _2 15:04:05 -0700 2006",
+	"Mon Jan _2 15:04:05.000000000 -0700 2006",
+}
+
+// zoneOffsets maps common timezone abbreviations to numeric offsets. Go only
+// resolves abbreviations it knows from the local zone, so anything else would
+// silently parse as UTC.
+var zoneOffsets = map[string]string{
+	"UT": "+0000",
+	"UTC": "+0000",
+	"GMT": "+0000",
+	"Z": "+0000",
+	"WET": "+0000",
+	"WEST": "+0100",
+	"BST": "+0100",
+	"IST": "+0530",
+	"CET": "+0100",
+	"CEST": "+0200",
+	"MET": "+0100",
+	"MEST": "+0200",
+	"EET": "+0200",
+	"EEST": "+0300",
+	"MSK": "+0300",
+	"SGT": "+0800",
+	"HKT": "+0800",
+	"AWST": "+0800",
+	"JST": "+0900",
+	"KST": "+0900",
+	"ACST": "+0930",
+	"ACDT": "+1030",
+	"AEST": "+1000",
+	"AEDT": "+1100",
+	"NZST": "+1200",
+	"NZDT": "+1300",
+	"AST": "-0400",
+	"ADT": "-0300",
+	"EST": "-0500",
+	"EDT": "-0400",
+	"CST": "-0600",
+	"CDT": "-0500",
+	"MST": "-0700",
+	"MDT": "-0600",
+	"PST": "-0800",
+	"PDT": "-0700",
+	"AKST": "-0900",
+	"AKDT": "-0800",
+	"HST": "-1000",
+}
+
+// ParseDate parses a publication date in any of the layouts commonly found
+// in real-world feeds. The returned time is in UTC.
+func ParseDate(value string) (time.Time, error) {
+	normalized := normalizeDate(value)
+	if normalized == "" {
+		return time.Time{}, fmt.Errorf("empty date")
+	}
+	for _, layout := range dateLayouts {
+		parsed, err := time.Parse(layout, normalized)
+		if err == nil {
+			return parsed.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date format: %q", value)
+}
+
+// normalizeDate collapses whitespace, drops a leading weekday name in any
+// language, drops trailing zone comments like "(UTC)", writes 12-hour clock
+// markers as a separate "AM" or "PM" and replaces timezone abbreviations with
+// numeric offsets.
+func normalizeDate(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if i := strings.Index(value, "("); i > 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	if i := strings.Index(value, ","); i > 0 && isWord(value[:i]) {
+		value = strings.TrimSpace(value[i+1:])
+	}
+	var fields []string
+	for _, field := range strings.Fields(value) {
+		if clock, marker, ok := splitMeridiem(field); ok {
+			if clock != "" {
+				fields = append(fields, clock)
+			}
+			fields = append(fields, marker)
+		} else if offset, ok := zoneOffsets[strings.ToUpper(field)]; ok {
+			fields = append(fields, offset)
+		} else {
+			fields = append(fields, field)
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// splitMeridiem splits a 12-hour clock marker such as "pm", "P.M." or the
+// end of "3:04pm" from field and returns it as "AM" or "PM".
+func splitMeridiem(field string) (clock, marker string, ok bool) {
+	upper := strings.ToUpper(strings.ReplaceAll(field, ".", ""))
+	for _, marker := range []string{"AM", "PM"} {
+		clock, found := strings.CutSuffix(upper, marker)
+		if !found {
+			continue
+		}
+		if clock == "" || strings.Contains(clock, ":") {
+			return clock, marker, true
+		}
+	}
+	return "", "", false
+}
+
+// isWord reports whether s is made up only of letters and dots, as a weekday
+// name or abbreviation would be.
+func isWord(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) && r != '.' {
+			return false
+		}
+	}
+	return true
+}
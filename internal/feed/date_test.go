@@ -0,0 +1,57 @@
+package feed
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		value	string
+		want	time.Time
+	}{
+		{"Mon, 02 Jan 2006 15:04:05 +0000", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"Mon, 02 Jan 2006 15:04:05 GMT", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"Tue, 10 Jun 2003 04:00:00 EDT", time.Date(2003, 6, 10, 8, 0, 0, 0, time.UTC)},
+		{"Sat, 07 Sep 2002 00:00:01 PST", time.Date(2002, 9, 7, 8, 0, 1, 0, time.UTC)},
+		{"Wed, 4 Jul 2001 12:08 -0700", time.Date(2001, 7, 4, 19, 8, 0, 0, time.UTC)},
+		{"Thu, 01 Jan 04 19:48:21 +0100", time.Date(2004, 1, 1, 18, 48, 21, 0, time.UTC)},
+		{"Fri, 21 Nov 1997 09:55:06 -0600 (CST)", time.Date(1997, 11, 21, 15, 55, 6, 0, time.UTC)},
+		{"  Sun,   19  May 2002  15:21:36  GMT ", time.Date(2002, 5, 19, 15, 21, 36, 0, time.UTC)},
+		{"Tuesday, 5 March 2024 10:00:00 +0100", time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)},
+		{"5-Mar-24 10:00:00 +0000", time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)},
+		{"2024-03-05T10:00:00Z", time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)},
+		{"2024-03-05T10:00:00.123+02:00", time.Date(2024, 3, 5, 8, 0, 0, 123000000, time.UTC)},
+		{"2024-03-05T10:00:00+0200", time.Date(2024, 3, 5, 8, 0, 0, 0, time.UTC)},
+		{"2024-03-05 10:00:00", time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)},
+		{"2024-03-05", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{"March 5, 2024", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{"Monday, January 2, 2006 3:04 PM", time.Date(2006, 1, 2, 15, 4, 0, 0, time.UTC)},
+		{"Monday, January 2, 2006 3:04:05 AM", time.Date(2006, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"January 2, 2006 12:30 AM", time.Date(2006, 1, 2, 0, 30, 0, 0, time.UTC)},
+		{"Jan 2, 2006 3:04pm EST", time.Date(2006, 1, 2, 20, 4, 0, 0, time.UTC)},
+		{"Jan 2, 2006 3:04 p.m.", time.Date(2006, 1, 2, 15, 4, 0, 0, time.UTC)},
+		{"Mon, 02 Jan 2006 03:04 PM +0100", time.Date(2006, 1, 2, 14, 4, 0, 0, time.UTC)},
+		{"01/02/2006 3:04:05 PM", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"January 2, 2006 15:04", time.Date(2006, 1, 2, 15, 4, 0, 0, time.UTC)},
+		{"Mon Jan  2 15:04:05 2006", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		got, err := ParseDate(test.value)
+		if err != nil {
+			t.Errorf("ParseDate(%q): %v", test.value, err)
+			continue
+		}
+		if !got.Equal(test.want) || got.Location() != time.UTC {
+			t.Errorf("ParseDate(%q) = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
+
+func TestParseDateRejects(t *testing.T) {
+	for _, value := range []string{"", "   ", "yesterday", "32 Jan 2006", "Di, 05 Mär 2024 10:00:00 +0100"} {
+		if got, err := ParseDate(value); err == nil {
+			t.Errorf("ParseDate(%q) = %v, want an error", value, got)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package feed
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/xml"
@@ -9,12 +10,15 @@ import (
 	"html"
 	"io"
 	"net/http"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/lib/pq"
 	"github.com/theMagicRabbit/gator/internal/database"
 	"github.com/theMagicRabbit/gator/internal/state"
+	"github.com/theMagicRabbit/gator/internal/store"
 )
 
 type RSSFeed struct {
@@ -27,28 +31,72 @@ type RSSFeed struct {
 }
 
 type RSSItem struct {
-	Title		string	`xml:"title"`
-	Link		string	`xml:"link"`
-	Description	string	`xml:"description"`
-	PubDate		string	`xml:"pubDate"`
+	Title		string		`xml:"title"`
+	Link		string		`xml:"link"`
+	Description	string		`xml:"description"`
+	PubDate		string		`xml:"pubDate"`
+	GUID		string		`xml:"guid"`
+	About		string		`xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	DCDate		string		`xml:"http://purl.org/dc/elements/1.1/ date"`
+	Creator		string		`xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Subject		[]string	`xml:"http://purl.org/dc/elements/1.1/ subject"`
+	Author		string		`xml:"author"`
+	Category	[]string	`xml:"category"`
 }
 
-func FetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
+// CacheValidators are the HTTP validators a server sent with a feed. They
+// are echoed back on the next fetch so unchanged feeds can answer 304.
+type CacheValidators struct {
+	ETag			string;
+	LastModified	string;
+}
+
+// FetchFeed downloads and parses a feed. When the server reports the feed is
+// unchanged since the given validators, the returned feed is nil.
+func FetchFeed(ctx context.Context, feedURL string, cache CacheValidators) (*RSSFeed, CacheValidators, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
 	if err != nil {
-		return nil, err
+		return nil, cache, err
+	}
+	if cache.ETag != "" {
+		req.Header.Set("If-None-Match", cache.ETag)
+	}
+	if cache.LastModified != "" {
+		req.Header.Set("If-Modified-Since", cache.LastModified)
 	}
 	res, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, cache, err
 	}
 	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotModified {
+		return nil, cache, nil
+	}
+	if res.StatusCode >= 400 {
+		return nil, cache, fmt.Errorf("fetching %s: %s", feedURL, res.Status)
+	}
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
-		return nil, err
+		return nil, cache, err
 	}
-	feed := new(RSSFeed)
-	err = xml.Unmarshal(body, feed)
+	feed, err := parseFeed(res.Header.Get("Content-Type"), body)
+	if err != nil {
+		return nil, cache, err
+	}
+	validators := CacheValidators{
+		ETag: res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+	}
+	return feed, validators, nil
+}
+
+// parseFeed decodes a feed body into an RSSFeed. JSON Feeds are detected by
+// the Content-Type header or, failing that, by sniffing the body.
+func parseFeed(contentType string, body []byte) (*RSSFeed, error) {
+	if isJSONFeed(contentType, body) {
+		return parseJSONFeed(body)
+	}
+	feed, err := parseXMLFeed(body)
 	if err != nil {
 		return nil, err
 	}
@@ -57,86 +105,392 @@ func FetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
 	for i, item := range feed.Channel.Item {
 		item.Description = html.UnescapeString(item.Description)
 		item.Title = html.UnescapeString(item.Title)
+		if item.PubDate == "" {
+			item.PubDate = item.DCDate
+		}
+		// dc:creator names the author, RSS author is usually an email address
+		if item.Creator != "" {
+			item.Author = item.Creator
+		}
+		item.Author = html.UnescapeString(strings.TrimSpace(item.Author))
+		item.Category = uniqueStrings(append(item.Category, item.Subject...))
 		feed.Channel.Item[i] = item
 	}
 	return feed, nil
 }
 
-func ScrapeFeeds(s *state.State) error {
-	next, err := s.Db.GetNextFeedToFetch(context.Background())
+// uniqueStrings trims the values and drops blank and repeated ones,
+// keeping the order they were first seen in
+func uniqueStrings(categories []string) []string {
+	var unique []string
+	for _, category := range categories {
+		category = strings.TrimSpace(category)
+		if category != "" && !slices.Contains(unique, category) {
+			unique = append(unique, category)
+		}
+	}
+	return unique
+}
+
+// rootElement returns the local name of the first element in an XML document
+func rootElement(body []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+// parseXMLFeed decodes an RSS 2.0, RSS 1.0 (RDF) or Atom document into an RSSFeed, detecting the
+// format from the root element.
+func parseXMLFeed(body []byte) (*RSSFeed, error) {
+	root, err := rootElement(body)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	switch root {
+	case "rss":
+		feed := new(RSSFeed)
+		err = xml.Unmarshal(body, feed)
+		if err != nil {
+			return nil, err
+		}
+		return feed, nil
+	case "feed":
+		atom := new(AtomFeed)
+		err = xml.Unmarshal(body, atom)
+		if err != nil {
+			return nil, err
+		}
+		return atom.toRSSFeed(), nil
+	case "RDF":
+		rdf := new(RDFFeed)
+		err = xml.Unmarshal(body, rdf)
+		if err != nil {
+			return nil, err
+		}
+		return rdf.toRSSFeed(), nil
+	default:
+		return nil, fmt.Errorf("unsupported feed format: <%s>", root)
+	}
+}
+
+// ScrapeSummary counts the outcome of a ScrapeFeeds run
+type ScrapeSummary struct {
+	Fetched		int;
+	NotModified	int;
+	Failed		int;
+	NewPosts	int;
+}
+
+// fetchTimeout bounds how long a single feed may take to fetch and store
+const fetchTimeout = 2 * time.Minute
+
+// ScrapeFeeds runs a pool of workers that claim and scrape feeds until no
+// feed last fetched before staleBefore remains. Claims use row locking, so
+// several agg processes can share one database. Once ctx is cancelled no new
+// feeds are claimed, but feeds already claimed are finished.
+func ScrapeFeeds(ctx context.Context, s *state.State, concurrency int, staleBefore time.Time) (ScrapeSummary, error) {
+	if concurrency < 1 {
+		concurrency = 1
 	}
-	feed, err := FetchFeed(context.Background(), next.Url)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	summary := ScrapeSummary{}
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			workerSummary, err := scrapeWorker(ctx, s, staleBefore)
+			mu.Lock()
+			defer mu.Unlock()
+			summary.Fetched += workerSummary.Fetched
+			summary.NotModified += workerSummary.NotModified
+			summary.Failed += workerSummary.Failed
+			summary.NewPosts += workerSummary.NewPosts
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return summary, errors.Join(errs...)
+}
+
+// scrapeWorker claims feeds one at a time until there are none left to fetch
+// or ctx is cancelled.
+func scrapeWorker(ctx context.Context, s *state.State, staleBefore time.Time) (ScrapeSummary, error) {
+	summary := ScrapeSummary{}
+	for ctx.Err() == nil {
+		params := database.ClaimNextFeedToFetchParams{
+			ClaimedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+			StaleBefore: sql.NullTime{Time: staleBefore.UTC(), Valid: true},
+		}
+		next, err := s.Db.ClaimNextFeedToFetch(ctx, params)
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, context.Canceled) {
+			return summary, nil
+		} else if err != nil {
+			return summary, err
+		}
+		// The claimed feed is finished even if ctx is cancelled meanwhile
+		feedCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fetchTimeout)
+		newPosts, err := ScrapeFeed(feedCtx, s, next)
+		if err != nil {
+			summary.Failed++
+			fmt.Printf("failed to scrape %s: %v\n", next.Url, err)
+			err = markFeedFailed(feedCtx, s, next, err)
+			cancel()
+			if err != nil {
+				return summary, err
+			}
+			continue
+		}
+		cancel()
+		summary.Fetched++
+		if newPosts < 0 {
+			summary.NotModified++
+		} else {
+			summary.NewPosts += newPosts
+		}
+	}
+	return summary, nil
+}
+
+// Backoff bounds for feeds that fail to fetch or parse
+const (
+	minFailureBackoff = 5 * time.Minute
+	maxFailureBackoff = 24 * time.Hour
+)
+
+// failureBackoff returns how long to wait before retrying a feed that has
+// failed the given number of times in a row.
+func failureBackoff(failures int32) time.Duration {
+	backoff := minFailureBackoff
+	for i := int32(1); i < failures && backoff < maxFailureBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxFailureBackoff)
+}
+
+// markFeedFailed records a failed scrape and schedules the next attempt with
+// exponential backoff.
+func markFeedFailed(ctx context.Context, s *state.State, failed database.Feed, scrapeErr error) error {
+	utcNow := time.Now().UTC()
+	nextFetch := utcNow.Add(failureBackoff(failed.ConsecutiveFailures + 1))
+	params := database.MarkFeedFailedParams{
+		ID: failed.ID,
+		UpdatedAt: utcNow,
+		LastError: sql.NullString{String: scrapeErr.Error(), Valid: true},
+		NextFetchAt: sql.NullTime{Time: nextFetch, Valid: true},
+	}
+	_, err := s.Db.MarkFeedFailed(ctx, params)
+	return err
+}
+
+// ScrapeFeed fetches a single feed and stores any new posts. It returns the
+// number of posts stored, or -1 if the feed was not modified. The posts and
+// the cache validators are stored in one transaction, so a feed is never
+// marked fetched with only some of its posts stored.
+func ScrapeFeed(ctx context.Context, s *state.State, next database.Feed) (int, error) {
+	cache := CacheValidators{
+		ETag: next.Etag.String,
+		LastModified: next.LastModified.String,
+	}
+	feed, cache, err := FetchFeed(ctx, next.Url, cache)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	utcTimestamp := time.Now().UTC()
 	params := database.MarkFeedFetchedParams{
 		ID: next.ID,
 		UpdatedAt: utcTimestamp,
+		Etag: sql.NullString{String: cache.ETag, Valid: cache.ETag != ""},
+		LastModified: sql.NullString{String: cache.LastModified, Valid: cache.LastModified != ""},
 	}
-	_, err = s.Db.MarkFeedFetched(context.Background(), params)
-	if err != nil { 
-		return err
-	}
-	for _, item := range feed.Channel.Item {
-		err = savePost(s, next, item)
+	newPosts := 0
+	err = s.Db.InTx(ctx, func(qtx store.Store) error {
+		_, err := qtx.MarkFeedFetched(ctx, params)
 		if err != nil {
-			var pqErr *pq.Error
-			if errors.As(err, &pqErr) {
-				// If the URl already exists, we can safely skip the error
-				if pqErr.Code == "23505" {
-					continue
-				}
-			} else {
+			return err
+		}
+		if feed == nil {
+			return nil
+		}
+		err = lockPostURLs(ctx, qtx, feed.Channel.Item)
+		if err != nil {
+			return err
+		}
+		for _, item := range feed.Channel.Item {
+			created, err := savePost(ctx, qtx, next, item)
+			if err != nil {
 				return err
 			}
+			if created {
+				newPosts++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if feed == nil {
+		fmt.Printf("%s not modified since last fetch\n", next.Name)
+		return -1, nil
+	}
+	return newPosts, nil
+}
+
+// adoptLegacyPost gives the item's guid to a post stored from the feed
+// before guids were used, which the migration marked with its url. It
+// reports whether there was such a post.
+func adoptLegacyPost(ctx context.Context, db store.Store, feed database.Feed, item RSSItem, guid string) (bool, error) {
+	if item.Link == "" {
+		return false, nil
+	}
+	for _, url := range slices.Compact([]string{item.Link, NormalizeURL(item.Link)}) {
+		params := database.AdoptLegacyPostFeedParams{
+			Guid: guid,
+			FeedID: feed.ID,
+			Url: url,
+		}
+		_, err := db.AdoptLegacyPostFeed(ctx, params)
+		if err == nil {
+			return true, nil
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// lockPostURLs locks the normalized URL of every item until the transaction
+// ends. Another worker storing the same article from a different feed then
+// waits for this transaction and finds its post, rather than missing it and
+// storing the article twice. Locks are taken in sorted order, so two workers
+// never wait on each other.
+func lockPostURLs(ctx context.Context, db store.Store, items []RSSItem) error {
+	var urls []string
+	for _, item := range items {
+		if item.Link != "" {
+			urls = append(urls, NormalizeURL(item.Link))
+		}
+	}
+	slices.Sort(urls)
+	for _, url := range slices.Compact(urls) {
+		err := db.LockPostURL(ctx, url)
+		if err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-func savePost(s *state.State, feed database.Feed, item RSSItem) error {
+// savePost stores an item and links it to the feed it came from. An article
+// that is already stored from another feed is linked rather than duplicated;
+// articles are matched by normalized URL. A post this feed already links to
+// is never reused, so two items of one feed sharing a URL are both kept.
+// It reports whether the feed gained a post.
+func savePost(ctx context.Context, db store.Store, feed database.Feed, item RSSItem) (bool, error) {
+	if item.Title == "" && item.Description == "" {
+		// posts need a title or a description to be shown
+		return false, nil
+	}
 	utcNow := time.Now().UTC()
+	guid := itemGUID(item)
+	existsParams := database.PostFeedExistsParams{
+		FeedID: feed.ID,
+		Guid: guid,
+	}
+	exists, err := db.PostFeedExists(ctx, existsParams)
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+	adopted, err := adoptLegacyPost(ctx, db, feed, item, guid)
+	if err != nil || adopted {
+		return false, err
+	}
 	itemTitle := sql.NullString{}
 	itemUrl := sql.NullString{}
 	itemDescription := sql.NullString{}
 	itemPubDate := sql.NullTime{}
+	itemAuthor := sql.NullString{}
 	if item.Title != "" {
 		itemTitle.String = item.Title
 		itemTitle.Valid = true
 	}
 	if item.Link != "" {
-		itemUrl.String = item.Link
+		itemUrl.String = NormalizeURL(item.Link)
 		itemUrl.Valid = true
 	}
 	if item.Description != "" {
 		itemDescription.String = item.Description
 		itemDescription.Valid = true
 	}
-	pubDate, err := time.Parse(time.RFC1123Z, item.PubDate)
-	if err == nil {
-		if !pubDate.IsZero() {
-			itemPubDate.Time = pubDate
-			itemPubDate.Valid = true
-		}
+	if item.Author != "" {
+		itemAuthor.String = item.Author
+		itemAuthor.Valid = true
 	}
+	pubDate, err := ParseDate(item.PubDate)
+	if err != nil || pubDate.IsZero() {
+		// Fall back to the fetch time so undated posts still sort sensibly
+		pubDate = utcNow
+	}
+	itemPubDate.Time = pubDate
+	itemPubDate.Valid = true
 
-	params := database.CreatePostParams{
-		ID: uuid.New(),
-		CreatedAt: utcNow,
-		UpdatedAt: utcNow,
-		Title: itemTitle,
-		Url: itemUrl,
-		Description: itemDescription,
-		PublishedAt: itemPubDate,
+	var post database.Post
+	err = sql.ErrNoRows
+	if itemUrl.Valid {
+		// Posts stored before links were normalized keep the link as given
+		candidates := slices.Compact([]string{itemUrl.String, item.Link})
+		for _, url := range candidates {
+			params := database.GetPostByURLNotInFeedParams{
+				Url: sql.NullString{String: url, Valid: true},
+				FeedID: feed.ID,
+			}
+			post, err = db.GetPostByURLNotInFeed(ctx, params)
+			if !errors.Is(err, sql.ErrNoRows) {
+				break
+			}
+		}
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		params := database.CreatePostParams{
+			ID: uuid.New(),
+			CreatedAt: utcNow,
+			UpdatedAt: utcNow,
+			Title: itemTitle,
+			Url: itemUrl,
+			Description: itemDescription,
+			PublishedAt: itemPubDate,
+			Author: itemAuthor,
+			Categories: item.Category,
+		}
+		post, err = db.CreatePost(ctx, params)
+	}
+	if err != nil {
+		return false, err
+	}
+	linkParams := database.CreatePostFeedParams{
+		PostID: post.ID,
 		FeedID: feed.ID,
+		Guid: guid,
+		CreatedAt: utcNow,
 	}
-	post, err := s.Db.CreatePost(context.Background(), params)
+	_, err = db.CreatePostFeed(ctx, linkParams)
 	if err != nil {
-		return err
+		return false, err
 	}
 	fmt.Println(post)
-	return nil
+	return true, nil
 }
@@ -0,0 +1,588 @@
+package feed
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pressly/goose/v3"
+	"github.com/theMagicRabbit/gator/internal/config"
+	"github.com/theMagicRabbit/gator/internal/database"
+	"github.com/theMagicRabbit/gator/internal/state"
+	"github.com/theMagicRabbit/gator/internal/store"
+)
+
+// feedServer serves an RSS document that tests can change, and answers 304
+// while its ETag is unchanged.
+type feedServer struct {
+	mu			sync.Mutex
+	body		string
+	etag		string
+	version		int
+	status		int
+	fetches		int
+	*httptest.Server
+}
+
+func newFeedServer(t *testing.T, items ...string) *feedServer {
+	t.Helper()
+	server := &feedServer{}
+	server.setItems(items...)
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		server.fetches++
+		if server.status != 0 {
+			w.WriteHeader(server.status)
+			return
+		}
+		if r.Header.Get("If-None-Match") == server.etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Header().Set("ETag", server.etag)
+		fmt.Fprint(w, server.body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// setItems replaces the items the server sends, and its ETag with them
+func (f *feedServer) setItems(items ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.body = `<?xml version="1.0"?><rss version="2.0"><channel><title>Test</title>` + strings.Join(items, "") + `</channel></rss>`
+	f.version++
+	f.etag = fmt.Sprintf(`"%d"`, f.version)
+}
+
+func rssItem(guid, title, link string) string {
+	return fmt.Sprintf(`<item><guid>%s</guid><title>%s</title><link>%s</link><pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate></item>`, guid, title, link)
+}
+
+func newTestState(t *testing.T) (*state.State, database.User) {
+	t.Helper()
+	return newTestStateWith(t, store.NewMemory())
+}
+
+func newTestStateWith(t *testing.T, db store.Store) (*state.State, database.User) {
+	t.Helper()
+	s := &state.State{Config: &config.Config{}, Db: db}
+	now := time.Now().UTC()
+	user, err := s.Db.CreateUser(context.Background(), database.CreateUserParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: "gator"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, user
+}
+
+// testStores returns the stores that ingestion tests run against: memory
+// and a migrated SQLite database.
+func testStores(t *testing.T) map[string]store.Store {
+	t.Helper()
+	db, err := database.OpenSQLite(filepath.Join(t.TempDir(), "gator.db"), os.DirFS("../../sql/sqlite/queries"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	err = goose.SetDialect("sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	goose.SetLogger(goose.NopLogger())
+	err = goose.Up(db, "../../sql/sqlite/schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]store.Store{
+		"memory": store.NewMemory(),
+		"sqlite": store.NewSQL(db),
+	}
+}
+
+// addFeed adds a feed the user follows
+func addFeed(t *testing.T, s *state.State, user database.User, url string) database.Feed {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().UTC()
+	feed, err := s.Db.CreateFeed(ctx, database.CreateFeedParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: url, Url: url, UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Db.CreateFeedFollows(ctx, database.CreateFeedFollowsParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, UserID: user.ID, FeedID: feed.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return feed
+}
+
+// scrape fetches the feed as it is stored now, as agg would
+func scrape(t *testing.T, s *state.State, feed database.Feed) int {
+	t.Helper()
+	current, err := s.Db.GetFeedFromID(context.Background(), feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newPosts, err := ScrapeFeed(context.Background(), s, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newPosts
+}
+
+func postTitles(t *testing.T, s *state.State, user database.User) []string {
+	t.Helper()
+	posts, err := s.Db.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
+		UserID: user.ID,
+		IncludeRead: true,
+		PostLimit: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, post := range posts {
+		titles = append(titles, post.Title.String)
+	}
+	return titles
+}
+
+func TestScrapeFeed(t *testing.T) {
+	s, user := newTestState(t)
+	server := newFeedServer(t,
+		rssItem("1", "First", "https://example.com/1"),
+		rssItem("2", "Second", "https://example.com/2"),
+	)
+	feed := addFeed(t, s, user, server.URL)
+
+	if newPosts := scrape(t, s, feed); newPosts != 2 {
+		t.Errorf("first fetch stored %d posts, want 2", newPosts)
+	}
+	stored, err := s.Db.GetFeedFromID(context.Background(), feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Etag.String != server.etag || !stored.LastFetchedAt.Valid {
+		t.Errorf("feed fetch state is etag %q fetched %v, want etag %q", stored.Etag.String, stored.LastFetchedAt, server.etag)
+	}
+
+	if newPosts := scrape(t, s, feed); newPosts != -1 {
+		t.Errorf("unchanged feed stored %d posts, want -1 for not modified", newPosts)
+	}
+
+	server.setItems(
+		rssItem("1", "First", "https://example.com/1"),
+		rssItem("2", "Second", "https://example.com/2"),
+		rssItem("3", "Third", "https://example.com/3"),
+		`<item><guid>4</guid><link>https://example.com/empty</link></item>`,
+	)
+	if newPosts := scrape(t, s, feed); newPosts != 1 {
+		t.Errorf("changed feed stored %d posts, want 1", newPosts)
+	}
+	if titles := postTitles(t, s, user); len(titles) != 3 {
+		t.Errorf("posts are %q, want First, Second and Third", titles)
+	}
+}
+
+// TestScrapeFeedSharesPosts checks that an article in two feeds is stored
+// once and reaches the followers of both, even when the links differ in
+// tracking parameters.
+func TestScrapeFeedSharesPosts(t *testing.T) {
+	for name, db := range testStores(t) {
+		s, user := newTestStateWith(t, db)
+		first := newFeedServer(t, rssItem("a", "Shared", "https://example.com/shared"))
+		second := newFeedServer(t,
+			rssItem("b", "Shared", "https://EXAMPLE.com/shared?utm_source=rss#comments"),
+			rssItem("c", "Own", "https://example.com/own"),
+		)
+		firstFeed := addFeed(t, s, user, first.URL)
+		secondFeed := addFeed(t, s, user, second.URL)
+		if newPosts := scrape(t, s, firstFeed); newPosts != 1 {
+			t.Errorf("%s: first feed stored %d posts, want 1", name, newPosts)
+		}
+		if newPosts := scrape(t, s, secondFeed); newPosts != 2 {
+			t.Errorf("%s: second feed gained %d posts, want 2", name, newPosts)
+		}
+		if titles := postTitles(t, s, user); len(titles) != 2 {
+			t.Errorf("%s: posts are %q, want Shared once and Own", name, titles)
+		}
+		links, err := s.Db.BackupPostFeeds(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(links) != 3 {
+			t.Errorf("%s: %d post links, want 3", name, len(links))
+		}
+	}
+}
+
+// TestScrapeFeedKeepsItemsSharingURL checks that two items of one feed with
+// the same link but different guids are both stored, even when another feed
+// already has a post with that link.
+func TestScrapeFeedKeepsItemsSharingURL(t *testing.T) {
+	for name, db := range testStores(t) {
+		s, user := newTestStateWith(t, db)
+		other := newFeedServer(t, rssItem("x", "Elsewhere", "https://example.com/same"))
+		server := newFeedServer(t,
+			rssItem("a1", "First", "https://example.com/same"),
+			rssItem("a2", "Second", "https://example.com/same"),
+		)
+		scrape(t, s, addFeed(t, s, user, other.URL))
+		feed := addFeed(t, s, user, server.URL)
+		if newPosts := scrape(t, s, feed); newPosts != 2 {
+			t.Errorf("%s: stored %d posts, want 2", name, newPosts)
+		}
+		links, err := s.Db.BackupPostFeeds(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		guids := map[string]bool{}
+		for _, link := range links {
+			if link.FeedID == feed.ID {
+				guids[link.Guid] = true
+			}
+		}
+		if len(guids) != 2 || !guids["a1"] || !guids["a2"] {
+			t.Errorf("%s: feed links guids %v, want a1 and a2", name, guids)
+		}
+		// A refetch finds both by guid and stores nothing
+		server.setItems(
+			rssItem("a2", "Second", "https://example.com/same"),
+			rssItem("a1", "First", "https://example.com/same"),
+		)
+		if newPosts := scrape(t, s, feed); newPosts != 0 {
+			t.Errorf("%s: refetch stored %d posts, want 0", name, newPosts)
+		}
+	}
+}
+
+func TestScrapeFeeds(t *testing.T) {
+	s, user := newTestState(t)
+	working := newFeedServer(t, rssItem("1", "Works", "https://example.com/works"))
+	broken := newFeedServer(t)
+	broken.status = http.StatusInternalServerError
+	addFeed(t, s, user, working.URL)
+	brokenFeed := addFeed(t, s, user, broken.URL)
+
+	summary, err := ScrapeFeeds(context.Background(), s, 2, time.Now().UTC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ScrapeSummary{Fetched: 1, Failed: 1, NewPosts: 1}
+	if summary != want {
+		t.Errorf("summary is %+v, want %+v", summary, want)
+	}
+	failed, err := s.Db.GetFeedFromID(context.Background(), brokenFeed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed.ConsecutiveFailures != 1 || !failed.LastError.Valid || !failed.NextFetchAt.Valid {
+		t.Errorf("failed feed has %d failures, error %q, next fetch %v", failed.ConsecutiveFailures, failed.LastError.String, failed.NextFetchAt)
+	}
+
+	// Both feeds were just fetched or attempted, so nothing is due
+	summary, err = ScrapeFeeds(context.Background(), s, 2, time.Now().UTC().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary != (ScrapeSummary{}) {
+		t.Errorf("second run is %+v, want nothing fetched", summary)
+	}
+	if working.fetches != 1 || broken.fetches != 1 {
+		t.Errorf("feeds fetched %d and %d times, want once each", working.fetches, broken.fetches)
+	}
+}
+
+var errInjected = errors.New("injected failure")
+
+// failSecondLink fails the second CreatePostFeed call, to check that a
+// scrape keeps nothing when it fails partway.
+type failSecondLink struct {
+	store.Store
+	calls	*int
+}
+
+func (f failSecondLink) InTx(ctx context.Context, fn func(store.Store) error) error {
+	return f.Store.InTx(ctx, func(tx store.Store) error {
+		return fn(failSecondLink{Store: tx, calls: f.calls})
+	})
+}
+
+func (f failSecondLink) CreatePostFeed(ctx context.Context, arg database.CreatePostFeedParams) (database.PostFeed, error) {
+	*f.calls++
+	if *f.calls == 2 {
+		return database.PostFeed{}, errInjected
+	}
+	return f.Store.CreatePostFeed(ctx, arg)
+}
+
+func TestScrapeFeedRollsBack(t *testing.T) {
+	s, user := newTestState(t)
+	server := newFeedServer(t,
+		rssItem("1", "First", "https://example.com/1"),
+		rssItem("2", "Second", "https://example.com/2"),
+	)
+	feed := addFeed(t, s, user, server.URL)
+	db := s.Db
+	s.Db = failSecondLink{Store: db, calls: new(int)}
+	_, err := ScrapeFeed(context.Background(), s, feed)
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("ScrapeFeed returned %v, want the injected failure", err)
+	}
+	s.Db = db
+	if titles := postTitles(t, s, user); len(titles) != 0 {
+		t.Errorf("posts %q were kept after a failed scrape", titles)
+	}
+	if posts, _ := db.BackupPosts(context.Background()); len(posts) != 0 {
+		t.Errorf("%d posts were kept after a failed scrape", len(posts))
+	}
+	stored, err := db.GetFeedFromID(context.Background(), feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.LastFetchedAt.Valid || stored.Etag.Valid {
+		t.Error("feed was marked fetched although its posts were not stored")
+	}
+
+	// The next scrape is not told the feed is unchanged, so it stores both
+	if newPosts := scrape(t, s, feed); newPosts != 2 {
+		t.Errorf("scrape after the failure stored %d posts, want 2", newPosts)
+	}
+}
+
+// TestScrapeFeedAdoptsLegacyPosts checks that posts stored before guids were
+// used, which the migration marks with their url, are matched by url on the
+// next fetch rather than stored again.
+func TestScrapeFeedAdoptsLegacyPosts(t *testing.T) {
+	for name, db := range testStores(t) {
+		ctx := context.Background()
+		s, user := newTestStateWith(t, db)
+		server := newFeedServer(t,
+			rssItem("tag:example.com,2024:1", "First", "https://example.com/1"),
+			rssItem("tag:example.com,2024:2", "Second", "https://example.com/2"),
+		)
+		feed := addFeed(t, s, user, server.URL)
+		now := time.Now().UTC()
+		legacy, err := s.Db.CreatePost(ctx, database.CreatePostParams{
+			ID: uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			Title: sql.NullString{String: "First", Valid: true},
+			Url: sql.NullString{String: "https://example.com/1", Valid: true},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = s.Db.CreatePostFeed(ctx, database.CreatePostFeedParams{
+			PostID: legacy.ID,
+			FeedID: feed.ID,
+			Guid: "legacy:https://example.com/1",
+			CreatedAt: now,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if newPosts := scrape(t, s, feed); newPosts != 1 {
+			t.Errorf("%s: stored %d posts, want 1 as the first was stored before", name, newPosts)
+		}
+		if titles := postTitles(t, s, user); len(titles) != 2 {
+			t.Errorf("%s: posts are %q, want First and Second once each", name, titles)
+		}
+		exists, err := s.Db.PostFeedExists(ctx, database.PostFeedExistsParams{FeedID: feed.ID, Guid: "tag:example.com,2024:1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !exists {
+			t.Errorf("%s: legacy post did not take the item's guid", name)
+		}
+	}
+}
+
+// atomEntry wraps entry elements in an Atom feed document
+func atomEntry(entry string) string {
+	return `<feed xmlns="http://www.w3.org/2005/Atom"><title>Atom</title><entry><id>urn:1</id><title>T</title>` + entry + `</entry></feed>`
+}
+
+func TestParseAtomFeed(t *testing.T) {
+	tests := []struct {
+		name		string
+		entry		string
+		link		string
+		description	string
+		pubDate		string
+	}{
+		{
+			name: "alternate over other rels",
+			entry: `<link rel="self" href="https://example.com/self"/><link rel="enclosure" href="https://example.com/a.mp3"/><link rel="alternate" href="https://example.com/post"/>`,
+			link: "https://example.com/post",
+		},
+		{
+			name: "link without rel is alternate",
+			entry: `<link rel="related" href="https://example.com/related"/><link href="https://example.com/post"/>`,
+			link: "https://example.com/post",
+		},
+		{
+			name: "no alternate skips self and enclosure",
+			entry: `<link rel="self" href="https://example.com/self"/><link rel="related" href="https://example.com/related"/>`,
+			link: "https://example.com/related",
+		},
+		{
+			name: "only self",
+			entry: `<link rel="self" href="https://example.com/self"/>`,
+			link: "https://example.com/self",
+		},
+		{
+			name: "summary over content",
+			entry: `<summary>Short</summary><content type="html">&lt;p&gt;Long&lt;/p&gt;</content>`,
+			description: "Short",
+		},
+		{
+			name: "content without summary",
+			entry: `<content type="html">&lt;p&gt;Long&lt;/p&gt;</content>`,
+			description: "<p>Long</p>",
+		},
+		{
+			name: "xhtml content",
+			entry: `<content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Inline</p></div></content>`,
+			description: `<div xmlns="http://www.w3.org/1999/xhtml"><p>Inline</p></div>`,
+		},
+		{
+			name: "published over updated",
+			entry: `<updated>2024-02-01T00:00:00Z</updated><published>2024-01-01T00:00:00Z</published>`,
+			pubDate: "2024-01-01T00:00:00Z",
+		},
+		{
+			name: "updated without published",
+			entry: `<updated> 2024-02-01T00:00:00Z </updated>`,
+			pubDate: "2024-02-01T00:00:00Z",
+		},
+	}
+	for _, test := range tests {
+		feed, err := parseFeed("application/atom+xml", []byte(atomEntry(test.entry)))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if len(feed.Channel.Item) != 1 {
+			t.Errorf("%s: %d items, want 1", test.name, len(feed.Channel.Item))
+			continue
+		}
+		item := feed.Channel.Item[0]
+		if item.Link != test.link || item.Description != test.description || item.PubDate != test.pubDate {
+			t.Errorf("%s: link %q description %q date %q, want %q %q %q", test.name, item.Link, item.Description, item.PubDate, test.link, test.description, test.pubDate)
+		}
+		if item.GUID != "urn:1" || item.Title != "T" || feed.Channel.Title != "Atom" {
+			t.Errorf("%s: guid %q title %q in %q", test.name, item.GUID, item.Title, feed.Channel.Title)
+		}
+	}
+}
+
+func TestParseFeedAuthorsAndCategories(t *testing.T) {
+	tests := []struct {
+		name		string
+		body		string
+		author		string
+		categories	[]string
+	}{
+		{
+			name: "rss author",
+			body: `<rss version="2.0"><channel><item><title>T</title><author>jane@example.com (Jane)</author><category>go</category><category> db </category></item></channel></rss>`,
+			author: "jane@example.com (Jane)",
+			categories: []string{"go", "db"},
+		},
+		{
+			name: "dublin core",
+			body: `<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/"><channel><item><title>T</title><author>jane@example.com</author><dc:creator>Jane &amp; John</dc:creator><category>go</category><dc:subject>go</dc:subject><dc:subject>sql</dc:subject></item></channel></rss>`,
+			author: "Jane & John",
+			categories: []string{"go", "sql"},
+		},
+		{
+			name: "rdf",
+			body: `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/"><item rdf:about="https://example.com/1"><title>T</title><dc:creator>Jane</dc:creator><dc:subject>news</dc:subject></item></rdf:RDF>`,
+			author: "Jane",
+			categories: []string{"news"},
+		},
+		{
+			name: "atom",
+			body: `<feed xmlns="http://www.w3.org/2005/Atom"><entry><title>T</title><author><name>Jane</name></author><author><name>John</name></author><category term="go"/><category label="Databases"/><category term="go"/></entry></feed>`,
+			author: "Jane, John",
+			categories: []string{"go", "Databases"},
+		},
+		{
+			name: "none",
+			body: `<rss version="2.0"><channel><item><title>T</title></item></channel></rss>`,
+		},
+	}
+	for _, test := range tests {
+		feed, err := parseFeed("application/xml", []byte(test.body))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if len(feed.Channel.Item) != 1 {
+			t.Errorf("%s: %d items, want 1", test.name, len(feed.Channel.Item))
+			continue
+		}
+		item := feed.Channel.Item[0]
+		if item.Author != test.author || !slices.Equal(item.Category, test.categories) {
+			t.Errorf("%s: author %q categories %q, want %q %q", test.name, item.Author, item.Category, test.author, test.categories)
+		}
+	}
+}
+
+// TestScrapeFeedStoresAuthors checks that item authors and categories are
+// stored and reach the API readers.
+func TestScrapeFeedStoresAuthors(t *testing.T) {
+	for name, db := range testStores(t) {
+		s, user := newTestStateWith(t, db)
+		server := newFeedServer(t,
+			`<item><guid>1</guid><title>First</title><link>https://example.com/1</link><author>Jane</author><category>go</category><category>sql</category></item>`,
+			rssItem("2", "Second", "https://example.com/2"),
+		)
+		feed := addFeed(t, s, user, server.URL)
+		scrape(t, s, feed)
+
+		ctx := context.Background()
+		posts, err := s.Db.GetPostsForUser(ctx, database.GetPostsForUserParams{UserID: user.ID, IncludeRead: true, PostLimit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		items, err := s.Db.GetReaderItemsForUser(ctx, database.GetReaderItemsForUserParams{UserID: user.ID, ItemLimit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(posts) != 2 || len(items) != 2 {
+			t.Fatalf("%s: %d posts and %d items, want 2", name, len(posts), len(items))
+		}
+		for i, post := range posts {
+			want := struct {
+				author		string
+				categories	[]string
+			}{}
+			if post.Title.String == "First" {
+				want.author = "Jane"
+				want.categories = []string{"go", "sql"}
+			}
+			if post.Author.String != want.author || post.Author.Valid != (want.author != "") || !slices.Equal(post.Categories, want.categories) {
+				t.Errorf("%s: post %q has author %v categories %q, want %q %q", name, post.Title.String, post.Author, post.Categories, want.author, want.categories)
+			}
+			item := items[i]
+			if item.Author != post.Author || !slices.Equal(item.Categories, post.Categories) {
+				t.Errorf("%s: reader item %q has author %v categories %q, want %v %q", name, item.Title.String, item.Author, item.Categories, post.Author, post.Categories)
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package feed
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/url"
+	"strings"
+)
+
+// trackingParams are query parameters that identify a campaign or referrer
+// rather than the resource, so they are dropped when normalizing URLs.
+var trackingParams = map[string]bool{
+	"fbclid": true,
+	"gclid": true,
+	"mc_cid": true,
+	"mc_eid": true,
+}
+
+// NormalizeURL lowercases the scheme and host, drops the fragment and strips
+// tracking query parameters so that the same article reached through
+// different campaign links compares equal.
+func NormalizeURL(rawURL string) string {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return strings.TrimSpace(rawURL)
+	}
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	parsed.Host = strings.ToLower(parsed.Host)
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+	query := parsed.Query()
+	for key := range query {
+		if strings.HasPrefix(strings.ToLower(key), "utm_") || trackingParams[strings.ToLower(key)] {
+			query.Del(key)
+		}
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// itemGUID returns the identity of an item within its feed. The feed's own
+// guid is preferred; items without one fall back to their normalized link
+// and, failing that, a hash of their content.
+func itemGUID(item RSSItem) string {
+	if guid := strings.TrimSpace(item.GUID); guid != "" {
+		return guid
+	}
+	if item.Link != "" {
+		return NormalizeURL(item.Link)
+	}
+	sum := sha1.Sum([]byte(item.Title + "\n" + item.Description))
+	return "sha1:" + hex.EncodeToString(sum[:])
+}
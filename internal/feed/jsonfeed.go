@@ -0,0 +1,103 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+type JSONFeed struct {
+	Version		string			`json:"version"`
+	Title		string			`json:"title"`
+	HomePageURL	string			`json:"home_page_url"`
+	Description	string			`json:"description"`
+	Items		[]JSONFeedItem	`json:"items"`
+}
+
+type JSONFeedItem struct {
+	ID				string				`json:"id"`
+	URL				string				`json:"url"`
+	ExternalURL		string				`json:"external_url"`
+	Title			string				`json:"title"`
+	ContentHTML		string				`json:"content_html"`
+	ContentText		string				`json:"content_text"`
+	Summary			string				`json:"summary"`
+	DatePublished	string				`json:"date_published"`
+	DateModified	string				`json:"date_modified"`
+	Author			*JSONFeedAuthor		`json:"author"`
+	Authors			[]JSONFeedAuthor	`json:"authors"`
+	Tags			[]string			`json:"tags"`
+}
+
+// JSONFeedAuthor is an item author. Version 1.0 has a single author object,
+// version 1.1 replaces it with an authors array.
+type JSONFeedAuthor struct {
+	Name	string	`json:"name"`
+}
+
+// isJSONFeed reports whether a response should be decoded as a JSON Feed
+func isJSONFeed(contentType string, body []byte) bool {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		switch mediaType {
+		case "application/feed+json", "application/json":
+			return true
+		}
+	}
+	return bytes.HasPrefix(bytes.TrimSpace(body), []byte("{"))
+}
+
+// parseJSONFeed decodes a JSON Feed 1.0 or 1.1 document into an RSSFeed
+func parseJSONFeed(body []byte) (*RSSFeed, error) {
+	jsonFeed := JSONFeed{}
+	err := json.Unmarshal(body, &jsonFeed)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(jsonFeed.Version, "https://jsonfeed.org/version/") {
+		return nil, fmt.Errorf("unsupported JSON feed version: %q", jsonFeed.Version)
+	}
+	return jsonFeed.toRSSFeed(), nil
+}
+
+// toRSSFeed normalizes a JSON Feed into the RSSFeed item model
+func (j *JSONFeed) toRSSFeed() *RSSFeed {
+	feed := new(RSSFeed)
+	feed.Channel.Title = j.Title
+	feed.Channel.Link = j.HomePageURL
+	feed.Channel.Description = j.Description
+	for _, entry := range j.Items {
+		item := RSSItem{
+			Title: entry.Title,
+			Link: entry.URL,
+			Description: entry.ContentHTML,
+			PubDate: entry.DatePublished,
+			GUID: entry.ID,
+		}
+		if item.Link == "" {
+			item.Link = entry.ExternalURL
+		}
+		if item.Description == "" {
+			item.Description = entry.ContentText
+		}
+		if item.Description == "" {
+			item.Description = entry.Summary
+		}
+		if item.PubDate == "" {
+			item.PubDate = entry.DateModified
+		}
+		authors := entry.Authors
+		if entry.Author != nil {
+			authors = append([]JSONFeedAuthor{*entry.Author}, authors...)
+		}
+		var names []string
+		for _, author := range authors {
+			names = append(names, author.Name)
+		}
+		item.Author = strings.Join(uniqueStrings(names), ", ")
+		item.Category = uniqueStrings(entry.Tags)
+		feed.Channel.Item = append(feed.Channel.Item, item)
+	}
+	return feed
+}
@@ -0,0 +1,153 @@
+package feed
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestParseJSONFeed(t *testing.T) {
+	tests := []struct {
+		file		string
+		title		string
+		link		string
+		description	string
+		items		[]RSSItem
+	}{
+		{
+			file: "jsonfeed-1.0.json",
+			title: "Example Blog",
+			link: "https://example.org/",
+			description: "Notes from example.org",
+			items: []RSSItem{
+				{
+					GUID: "https://example.org/2017/05/17/html",
+					Link: "https://example.org/2017/05/17/html",
+					Title: "HTML content",
+					Description: "<p>Hello, <em>world</em>!</p>",
+					PubDate: "2017-05-17T10:02:12-07:00",
+					Author: "Jane Doe",
+					Category: []string{"greetings", "html"},
+				},
+				{
+					GUID: "2",
+					Link: "https://example.org/2017/05/18/text",
+					Title: "Text content",
+					Description: "Plain text only.",
+					PubDate: "2017-05-18T08:00:00Z",
+				},
+				{
+					GUID: "3",
+					Link: "https://elsewhere.example.com/article",
+					Title: "Link post",
+					Description: "Only a summary.",
+					PubDate: "2017-05-19T09:30:00+00:00",
+				},
+			},
+		},
+		{
+			file: "jsonfeed-1.1.json",
+			title: "Microblog",
+			link: "https://micro.example.net/",
+			items: []RSSItem{
+				{
+					GUID: "tag:micro.example.net,2020:1",
+					Link: "https://micro.example.net/2020/08/07/1.html",
+					Description: "<p>A post without a title.</p>",
+					PubDate: "2020-08-07T11:44:36-05:00",
+					Category: []string{"notes"},
+				},
+				{
+					GUID: "tag:micro.example.net,2020:2",
+					Link: "https://micro.example.net/2020/08/08/2.html",
+					Title: "Both links",
+					Description: "The url is the item's own page.",
+					PubDate: "2020-08-08T09:00:00Z",
+					Author: "John Doe, Richard Roe",
+				},
+				{
+					GUID: "tag:micro.example.net,2020:3",
+					Title: "No content",
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		feed, err := parseFeed("application/feed+json", readTestdata(t, test.file))
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+		channel := feed.Channel
+		if channel.Title != test.title || channel.Link != test.link || channel.Description != test.description {
+			t.Errorf("%s: channel %q %q %q, want %q %q %q", test.file, channel.Title, channel.Link, channel.Description, test.title, test.link, test.description)
+		}
+		if len(channel.Item) != len(test.items) {
+			t.Errorf("%s: %d items, want %d", test.file, len(channel.Item), len(test.items))
+			continue
+		}
+		for i, want := range test.items {
+			got := channel.Item[i]
+			if got.GUID != want.GUID || got.Link != want.Link || got.Title != want.Title || got.Description != want.Description || got.PubDate != want.PubDate || got.Author != want.Author || !slices.Equal(got.Category, want.Category) {
+				t.Errorf("%s: item %d is %+v, want %+v", test.file, i, got, want)
+			}
+		}
+	}
+}
+
+func TestParseFeedDetectsJSONFeed(t *testing.T) {
+	body := readTestdata(t, "jsonfeed-1.1.json")
+	tests := []struct {
+		name		string
+		contentType	string
+		body		[]byte
+	}{
+		{"feed+json", "application/feed+json", body},
+		{"json with charset", "application/json; charset=utf-8", body},
+		{"sniffed from text/plain", "text/plain", body},
+		{"sniffed without a content type", "", body},
+		{"sniffed from a mislabelled XML type", "application/rss+xml", body},
+		{"sniffed after leading whitespace", "", append([]byte("\n  \t"), body...)},
+	}
+	for _, test := range tests {
+		feed, err := parseFeed(test.contentType, test.body)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if feed.Channel.Title != "Microblog" || len(feed.Channel.Item) != 3 {
+			t.Errorf("%s: parsed %q with %d items", test.name, feed.Channel.Title, len(feed.Channel.Item))
+		}
+	}
+
+	rss := `<?xml version="1.0"?><rss version="2.0"><channel><title>RSS</title></channel></rss>`
+	feed, err := parseFeed("text/xml", []byte(rss))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Channel.Title != "RSS" {
+		t.Errorf("RSS parsed as %q", feed.Channel.Title)
+	}
+}
+
+func TestParseJSONFeedRejectsUnknownVersion(t *testing.T) {
+	for _, body := range []string{
+		`{"version": "1.1", "title": "No URL", "items": []}`,
+		`{"title": "No version", "items": []}`,
+	} {
+		_, err := parseFeed("application/feed+json", []byte(body))
+		if err == nil || !strings.Contains(err.Error(), "unsupported JSON feed version") {
+			t.Errorf("%s: got error %v", body, err)
+		}
+	}
+}
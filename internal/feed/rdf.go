@@ -0,0 +1,28 @@
+package feed
+
+// RDFFeed is an RSS 1.0 document. Unlike RSS 2.0, items are siblings of the
+// channel element rather than children of it.
+type RDFFeed struct {
+	Channel struct {
+		Title		string	`xml:"title"`
+		Link		string	`xml:"link"`
+		Description	string	`xml:"description"`
+	} `xml:"channel"`
+	Item	[]RSSItem	`xml:"item"`
+}
+
+// toRSSFeed normalizes an RSS 1.0 feed into the RSSFeed item model
+func (r *RDFFeed) toRSSFeed() *RSSFeed {
+	feed := new(RSSFeed)
+	feed.Channel.Title = r.Channel.Title
+	feed.Channel.Link = r.Channel.Link
+	feed.Channel.Description = r.Channel.Description
+	feed.Channel.Item = r.Item
+	for i, item := range feed.Channel.Item {
+		if item.GUID == "" {
+			item.GUID = item.About
+		}
+		feed.Channel.Item[i] = item
+	}
+	return feed
+}
@@ -0,0 +1,143 @@
+package opml
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+)
+
+type OPML struct {
+	XMLName	xml.Name	`xml:"opml"`
+	Version	string		`xml:"version,attr"`
+	Head	Head		`xml:"head"`
+	Body	Body		`xml:"body"`
+}
+
+type Head struct {
+	Title		string	`xml:"title,omitempty"`
+	DateCreated	string	`xml:"dateCreated,omitempty"`
+}
+
+type Body struct {
+	Outline	[]Outline	`xml:"outline"`
+}
+
+type Outline struct {
+	Text	string		`xml:"text,attr"`
+	Title	string		`xml:"title,attr,omitempty"`
+	Type	string		`xml:"type,attr,omitempty"`
+	XMLURL	string		`xml:"xmlUrl,attr,omitempty"`
+	HTMLURL	string		`xml:"htmlUrl,attr,omitempty"`
+	Outline	[]Outline	`xml:"outline"`
+}
+
+// Subscription is a feed found in an OPML document along with the folder it
+// was filed under.
+type Subscription struct {
+	Name		string;
+	URL			string;
+	Category	string;
+}
+
+// Parse reads an OPML 1.0 or 2.0 document
+func Parse(r io.Reader) (*OPML, error) {
+	doc := new(OPML)
+	err := xml.NewDecoder(r).Decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Subscriptions flattens the outline tree into the feeds it contains. Nested
+// folder names are joined with "/" to form the category.
+func (o *OPML) Subscriptions() []Subscription {
+	return collect(o.Body.Outline, "")
+}
+
+func collect(outlines []Outline, category string) []Subscription {
+	var subs []Subscription
+	for _, outline := range outlines {
+		if outline.XMLURL != "" {
+			subs = append(subs, Subscription{
+				Name: outline.name(),
+				URL: strings.TrimSpace(outline.XMLURL),
+				Category: category,
+			})
+			continue
+		}
+		folder := outline.name()
+		if category != "" && folder != "" {
+			folder = category + "/" + folder
+		} else if folder == "" {
+			folder = category
+		}
+		subs = append(subs, collect(outline.Outline, folder)...)
+	}
+	return subs
+}
+
+// name returns the display name of an outline. OPML 2.0 requires text, but
+// many exporters only set title.
+func (o Outline) name() string {
+	if name := strings.TrimSpace(o.Text); name != "" {
+		return name
+	}
+	return strings.TrimSpace(o.Title)
+}
+
+// New builds an OPML 2.0 document from a list of subscriptions. Categories
+// become folders, with "/" separating nested folders.
+func New(title string, subs []Subscription) *OPML {
+	doc := &OPML{
+		Version: "2.0",
+		Head: Head{
+			Title: title,
+			DateCreated: time.Now().UTC().Format(time.RFC1123Z),
+		},
+	}
+	for _, sub := range subs {
+		feed := Outline{
+			Text: sub.Name,
+			Title: sub.Name,
+			Type: "rss",
+			XMLURL: sub.URL,
+		}
+		outlines := &doc.Body.Outline
+		if sub.Category != "" {
+			for _, folder := range strings.Split(sub.Category, "/") {
+				outlines = &folderOutline(outlines, folder).Outline
+			}
+		}
+		*outlines = append(*outlines, feed)
+	}
+	return doc
+}
+
+// folderOutline returns the folder with the given name, adding it if needed
+func folderOutline(outlines *[]Outline, name string) *Outline {
+	for i := range *outlines {
+		if (*outlines)[i].XMLURL == "" && (*outlines)[i].Text == name {
+			return &(*outlines)[i]
+		}
+	}
+	*outlines = append(*outlines, Outline{Text: name, Title: name})
+	return &(*outlines)[len(*outlines)-1]
+}
+
+// Write encodes the document as indented XML
+func (o *OPML) Write(w io.Writer) error {
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(o)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
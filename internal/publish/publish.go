@@ -0,0 +1,284 @@
+package publish
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/theMagicRabbit/gator/internal/database"
+	"github.com/theMagicRabbit/gator/internal/state"
+)
+
+// Formats a stream can be written in
+const (
+	FormatRSS = "rss"
+	FormatAtom = "atom"
+)
+
+// DefaultLimit is the number of posts published when no limit is given
+const DefaultLimit = 50
+
+// Filter narrows the timeline that is published
+type Filter struct {
+	Category	string
+	Search		string
+	Limit		int
+}
+
+// Stream is a user's timeline ready to be written as a feed
+type Stream struct {
+	ID			string
+	Title		string
+	Link		string
+	Description	string
+	Author		string
+	Updated		time.Time
+	Entries		[]Entry
+}
+
+type Entry struct {
+	ID			string
+	Title		string
+	Link		string
+	Content		string
+	Author		string
+	Categories	[]string
+	Published	time.Time
+}
+
+// ForUser builds a stream from the posts of the feeds a user follows,
+// read or not, newest first.
+func ForUser(ctx context.Context, s *state.State, user database.User, filter Filter) (*Stream, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	posts, err := s.Db.GetPostsForUser(ctx, database.GetPostsForUserParams{
+		UserID: user.ID,
+		Category: sql.NullString{String: filter.Category, Valid: filter.Category != ""},
+		IncludeRead: true,
+		Search: sql.NullString{String: filter.Search, Valid: filter.Search != ""},
+		PostLimit: int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The stream ID must differ between filters of the same timeline
+	id := "urn:uuid:" + user.ID.String()
+	query := url.Values{}
+	title := fmt.Sprintf("gator stream for %s", user.Name)
+	if filter.Category != "" {
+		query.Set("category", filter.Category)
+		title = fmt.Sprintf("%s: %s", title, filter.Category)
+	}
+	if filter.Search != "" {
+		query.Set("q", filter.Search)
+		title = fmt.Sprintf("%s matching %q", title, filter.Search)
+	}
+	if len(query) > 0 {
+		id = fmt.Sprintf("%s?%s", id, query.Encode())
+	}
+	stream := &Stream{
+		ID: id,
+		Title: title,
+		Description: fmt.Sprintf("Posts from the feeds %s follows", user.Name),
+		Author: user.Name,
+		Updated: user.UpdatedAt,
+	}
+	for _, p := range posts {
+		published := p.CreatedAt
+		if p.PublishedAt.Valid {
+			published = p.PublishedAt.Time
+		}
+		if published.After(stream.Updated) {
+			stream.Updated = published
+		}
+		stream.Entries = append(stream.Entries, Entry{
+			ID: "urn:uuid:" + p.ID.String(),
+			Title: p.Title.String,
+			Link: p.Url.String,
+			Content: p.Description.String,
+			Author: p.Author.String,
+			Categories: p.Categories,
+			Published: published,
+		})
+	}
+	return stream, nil
+}
+
+// Write encodes the stream in the given format
+func (s *Stream) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatRSS:
+		return s.WriteRSS(w)
+	case FormatAtom:
+		return s.WriteAtom(w)
+	}
+	return fmt.Errorf("unknown feed format %s; use %s or %s", format, FormatRSS, FormatAtom)
+}
+
+// ContentType returns the media type of a format
+func ContentType(format string) string {
+	if format == FormatAtom {
+		return "application/atom+xml; charset=utf-8"
+	}
+	return "application/rss+xml; charset=utf-8"
+}
+
+type rssDocument struct {
+	XMLName	xml.Name	`xml:"rss"`
+	Version	string		`xml:"version,attr"`
+	DC		string		`xml:"xmlns:dc,attr"`
+	Channel	rssChannel	`xml:"channel"`
+}
+
+type rssChannel struct {
+	Title			string		`xml:"title"`
+	Link			string		`xml:"link"`
+	Description		string		`xml:"description"`
+	LastBuildDate	string		`xml:"lastBuildDate"`
+	Generator		string		`xml:"generator"`
+	Item			[]rssItem	`xml:"item"`
+}
+
+type rssItem struct {
+	Title		string		`xml:"title"`
+	Link		string		`xml:"link"`
+	Description	string		`xml:"description"`
+	Creator		string		`xml:"dc:creator,omitempty"`
+	Category	[]string	`xml:"category"`
+	PubDate		string		`xml:"pubDate"`
+	GUID		rssGUID		`xml:"guid"`
+}
+
+type rssGUID struct {
+	IsPermaLink	string	`xml:"isPermaLink,attr"`
+	Value		string	`xml:",chardata"`
+}
+
+// WriteRSS encodes the stream as an RSS 2.0 document. RSS channels must
+// link somewhere, so the stream needs a Link.
+func (s *Stream) WriteRSS(w io.Writer) error {
+	if s.Link == "" {
+		return fmt.Errorf("an %s feed needs a link", FormatRSS)
+	}
+	doc := rssDocument{
+		Version: "2.0",
+		DC: "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title: s.Title,
+			Link: s.Link,
+			Description: s.Description,
+			LastBuildDate: s.Updated.UTC().Format(time.RFC1123Z),
+			Generator: "gator",
+		},
+	}
+	for _, e := range s.Entries {
+		doc.Channel.Item = append(doc.Channel.Item, rssItem{
+			Title: e.Title,
+			Link: e.Link,
+			Description: e.Content,
+			Creator: e.Author,
+			Category: e.Categories,
+			PubDate: e.Published.UTC().Format(time.RFC1123Z),
+			GUID: rssGUID{IsPermaLink: "false", Value: e.ID},
+		})
+	}
+	return encode(w, doc)
+}
+
+type atomDocument struct {
+	XMLName		xml.Name	`xml:"http://www.w3.org/2005/Atom feed"`
+	ID			string		`xml:"id"`
+	Title		string		`xml:"title"`
+	Subtitle	string		`xml:"subtitle,omitempty"`
+	Updated		string		`xml:"updated"`
+	Author		atomAuthor	`xml:"author"`
+	Link		[]atomLink	`xml:"link"`
+	Generator	string		`xml:"generator"`
+	Entry		[]atomEntry	`xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name	string	`xml:"name"`
+}
+
+type atomLink struct {
+	Href	string	`xml:"href,attr"`
+	Rel		string	`xml:"rel,attr,omitempty"`
+}
+
+type atomCategory struct {
+	Term	string	`xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type	string	`xml:"type,attr"`
+	Value	string	`xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID			string			`xml:"id"`
+	Title		string			`xml:"title"`
+	Link		[]atomLink		`xml:"link"`
+	Published	string			`xml:"published"`
+	Updated		string			`xml:"updated"`
+	Author		*atomAuthor		`xml:"author"`
+	Category	[]atomCategory	`xml:"category"`
+	Content		atomContent		`xml:"content"`
+}
+
+// WriteAtom encodes the stream as an Atom 1.0 document
+func (s *Stream) WriteAtom(w io.Writer) error {
+	doc := atomDocument{
+		ID: s.ID,
+		Title: s.Title,
+		Subtitle: s.Description,
+		Updated: s.Updated.UTC().Format(time.RFC3339),
+		Author: atomAuthor{Name: s.Author},
+		Generator: "gator",
+	}
+	if s.Link != "" {
+		doc.Link = append(doc.Link, atomLink{Href: s.Link, Rel: "self"})
+	}
+	for _, e := range s.Entries {
+		entry := atomEntry{
+			ID: e.ID,
+			Title: e.Title,
+			Published: e.Published.UTC().Format(time.RFC3339),
+			Updated: e.Published.UTC().Format(time.RFC3339),
+			Content: atomContent{Type: "html", Value: e.Content},
+		}
+		if e.Link != "" {
+			entry.Link = append(entry.Link, atomLink{Href: e.Link, Rel: "alternate"})
+		}
+		if e.Author != "" {
+			entry.Author = &atomAuthor{Name: e.Author}
+		}
+		for _, category := range e.Categories {
+			entry.Category = append(entry.Category, atomCategory{Term: category})
+		}
+		doc.Entry = append(doc.Entry, entry)
+	}
+	return encode(w, doc)
+}
+
+// encode writes a document as indented XML
+func encode(w io.Writer, doc any) error {
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(doc)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,289 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theMagicRabbit/gator/internal/database"
+	"github.com/theMagicRabbit/gator/internal/store"
+)
+
+type User struct {
+	ID			uuid.UUID	`json:"id"`
+	CreatedAt	time.Time	`json:"created_at"`
+	Name		string		`json:"name"`
+}
+
+type Feed struct {
+	ID				uuid.UUID	`json:"id"`
+	CreatedAt		time.Time	`json:"created_at"`
+	Name			string		`json:"name"`
+	URL				string		`json:"url"`
+	UserID			uuid.UUID	`json:"user_id"`
+	LastFetchedAt	*time.Time	`json:"last_fetched_at"`
+}
+
+type Follow struct {
+	FeedID		uuid.UUID	`json:"feed_id"`
+	FeedName	string		`json:"feed_name"`
+	FeedURL		string		`json:"feed_url"`
+	Category	string		`json:"category,omitempty"`
+}
+
+type Post struct {
+	ID			uuid.UUID	`json:"id"`
+	Title		string		`json:"title"`
+	URL			string		`json:"url"`
+	Description	string		`json:"description"`
+	Author		string		`json:"author,omitempty"`
+	Categories	[]string	`json:"categories,omitempty"`
+	PublishedAt	*time.Time	`json:"published_at"`
+	Read		bool		`json:"read"`
+}
+
+// Page wraps a page of results from a list endpoint
+type Page[T any] struct {
+	Items	[]T	`json:"items"`
+	Limit	int	`json:"limit"`
+	Offset	int	`json:"offset"`
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func feedFromDB(f database.Feed) Feed {
+	return Feed{
+		ID: f.ID,
+		CreatedAt: f.CreatedAt,
+		Name: f.Name,
+		URL: f.Url,
+		UserID: f.UserID,
+		LastFetchedAt: nullTime(f.LastFetchedAt),
+	}
+}
+
+// paginate returns the requested page of an in-memory list
+func paginate[T any](items []T, limit, offset int) Page[T] {
+	page := Page[T]{
+		Items: []T{},
+		Limit: limit,
+		Offset: offset,
+	}
+	if offset < len(items) {
+		page.Items = items[offset:min(offset+limit, len(items))]
+	}
+	return page
+}
+
+func (srv *Server) handleUsers(w http.ResponseWriter, r *http.Request) {
+	names, err := srv.state.Db.GetAllUsers(r.Context())
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	limit, offset, err := pageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, paginate(names, limit, offset))
+}
+
+func (srv *Server) handleMe(w http.ResponseWriter, r *http.Request) {
+	user := requestUser(r)
+	respondWithJSON(w, http.StatusOK, User{
+		ID: user.ID,
+		CreatedAt: user.CreatedAt,
+		Name: user.Name,
+	})
+}
+
+func (srv *Server) handleFeeds(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := pageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	dbFeeds, err := srv.state.Db.GetAllFeeds(r.Context())
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	feeds := make([]Feed, 0, len(dbFeeds))
+	for _, f := range dbFeeds {
+		feeds = append(feeds, feedFromDB(f))
+	}
+	respondWithJSON(w, http.StatusOK, paginate(feeds, limit, offset))
+}
+
+func (srv *Server) handleCreateFeed(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Name	string	`json:"name"`
+		URL		string	`json:"url"`
+	}
+	params := parameters{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil || params.Name == "" || params.URL == "" {
+		respondWithError(w, http.StatusBadRequest, "name and url are required")
+		return
+	}
+	user := requestUser(r)
+	utcTime := time.Now().UTC()
+	var createFeed database.Feed
+	err = srv.state.Db.InTx(r.Context(), func(qtx store.Store) error {
+		createFeed, err = qtx.CreateFeed(r.Context(), database.CreateFeedParams{
+			ID: uuid.New(),
+			CreatedAt: utcTime,
+			UpdatedAt: utcTime,
+			Name: params.Name,
+			Url: params.URL,
+			UserID: user.ID,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = qtx.CreateFeedFollows(r.Context(), database.CreateFeedFollowsParams{
+			ID: uuid.New(),
+			CreatedAt: utcTime,
+			UpdatedAt: utcTime,
+			UserID: user.ID,
+			FeedID: createFeed.ID,
+		})
+		return err
+	})
+	if database.IsUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "feed already exists")
+		return
+	} else if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, feedFromDB(createFeed))
+}
+
+func (srv *Server) handleFollows(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := pageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	following, err := srv.state.Db.GetFeedFollowsForUser(r.Context(), requestUser(r).Name)
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	follows := make([]Follow, 0, len(following))
+	for _, f := range following {
+		follows = append(follows, Follow{
+			FeedID: f.FeedID,
+			FeedName: f.Feedname,
+			FeedURL: f.Feedurl,
+			Category: f.Category.String,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, paginate(follows, limit, offset))
+}
+
+func (srv *Server) handleCreateFollow(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		URL			string	`json:"url"`
+		Category	string	`json:"category"`
+	}
+	params := parameters{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil || params.URL == "" {
+		respondWithError(w, http.StatusBadRequest, "url is required")
+		return
+	}
+	feed, err := srv.state.Db.GetFeed(r.Context(), params.URL)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "feed not found")
+		return
+	} else if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	utcTime := time.Now().UTC()
+	following, err := srv.state.Db.CreateFeedFollows(r.Context(), database.CreateFeedFollowsParams{
+		ID: uuid.New(),
+		CreatedAt: utcTime,
+		UpdatedAt: utcTime,
+		UserID: requestUser(r).ID,
+		FeedID: feed.ID,
+		Category: sql.NullString{String: params.Category, Valid: params.Category != ""},
+	})
+	if database.IsUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "already following feed")
+		return
+	} else if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, Follow{
+		FeedID: feed.ID,
+		FeedName: feed.Name,
+		FeedURL: feed.Url,
+		Category: following.Category.String,
+	})
+}
+
+func (srv *Server) handleDeleteFollow(w http.ResponseWriter, r *http.Request) {
+	feedID, err := uuid.Parse(r.PathValue("feedID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid feed id")
+		return
+	}
+	err = srv.state.Db.DeleteFeedFollow(r.Context(), database.DeleteFeedFollowParams{
+		UserID: requestUser(r).ID,
+		FeedID: feedID,
+	})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (srv *Server) handlePosts(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := pageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	dbPosts, err := srv.state.Db.GetPostsForUser(r.Context(), database.GetPostsForUserParams{
+		UserID: requestUser(r).ID,
+		IncludeRead: r.URL.Query().Get("all") == "true",
+		PostLimit: int32(limit),
+		PostOffset: int32(offset),
+	})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	posts := make([]Post, 0, len(dbPosts))
+	for _, p := range dbPosts {
+		posts = append(posts, Post{
+			ID: p.ID,
+			Title: p.Title.String,
+			URL: p.Url.String,
+			Description: p.Description.String,
+			Author: p.Author.String,
+			Categories: p.Categories,
+			PublishedAt: nullTime(p.PublishedAt),
+			Read: p.IsRead,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, Page[Post]{
+		Items: posts,
+		Limit: limit,
+		Offset: offset,
+	})
+}
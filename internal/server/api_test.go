@@ -0,0 +1,200 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/theMagicRabbit/gator/internal/database"
+	"github.com/theMagicRabbit/gator/internal/store"
+)
+
+// api sends a JSON API request with the bearer token, or none when the
+// token is empty
+func (ts *testServer) api(t *testing.T, token, method, path, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL + "/api/" + path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer " + token)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.StatusCode, string(data)
+}
+
+// apiPage decodes a page of results from a list endpoint
+func apiPage[T any](t *testing.T, status int, body string) Page[T] {
+	t.Helper()
+	if status != http.StatusOK {
+		t.Fatalf("status %d, %s", status, body)
+	}
+	var page Page[T]
+	err := json.Unmarshal([]byte(body), &page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+func TestAPIAuth(t *testing.T) {
+	ts := newTestServer(t)
+	_, token := ts.addUser(t, "gator")
+
+	tests := []struct {
+		name	string
+		header	string
+		status	int
+	}{
+		{name: "no header", header: "", status: http.StatusUnauthorized},
+		{name: "not bearer", header: "Token " + token, status: http.StatusUnauthorized},
+		{name: "empty token", header: "Bearer ", status: http.StatusUnauthorized},
+		{name: "wrong token", header: "Bearer wrong", status: http.StatusUnauthorized},
+		{name: "token", header: "Bearer " + token, status: http.StatusOK},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodGet, ts.URL + "/api/me", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.header != "" {
+			req.Header.Set("Authorization", test.header)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != test.status {
+			t.Errorf("%s: status %d, want %d", test.name, res.StatusCode, test.status)
+		}
+		if test.status == http.StatusOK && !strings.Contains(string(body), `"name":"gator"`) {
+			t.Errorf("%s: response %s is not the token's user", test.name, body)
+		}
+	}
+}
+
+func TestAPIPagination(t *testing.T) {
+	ts := newTestServer(t)
+	user, token := ts.addUser(t, "gator")
+	feed := ts.addFeed(t, user, "https://example.com", "")
+	for i, title := range []string{"one", "two", "three"} {
+		ts.addPost(t, feed, title, time.Duration(i) * time.Hour)
+	}
+
+	tests := []struct {
+		query	string
+		want	[]string
+	}{
+		{query: "", want: []string{"one", "two", "three"}},
+		{query: "?limit=2", want: []string{"one", "two"}},
+		{query: "?limit=2&offset=2", want: []string{"three"}},
+		{query: "?offset=3", want: []string{}},
+	}
+	for _, test := range tests {
+		status, body := ts.api(t, token, http.MethodGet, "posts" + test.query, "")
+		page := apiPage[Post](t, status, body)
+		titles := []string{}
+		for _, post := range page.Items {
+			titles = append(titles, post.Title)
+		}
+		if !slices.Equal(titles, test.want) {
+			t.Errorf("posts%s are %q, want %q", test.query, titles, test.want)
+		}
+	}
+
+	for _, query := range []string{"limit=0", "limit=101", "limit=many", "offset=-1"} {
+		status, _ := ts.api(t, token, http.MethodGet, "posts?" + query, "")
+		if status != http.StatusBadRequest {
+			t.Errorf("posts?%s got status %d, want %d", query, status, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestAPIFollows(t *testing.T) {
+	ts := newTestServer(t)
+	owner, _ := ts.addUser(t, "owner")
+	feed := ts.addFeed(t, owner, "https://example.com", "")
+	_, token := ts.addUser(t, "gator")
+
+	status, body := ts.api(t, token, http.MethodPost, "follows", `{"url": "https://example.com", "category": "News"}`)
+	if status != http.StatusCreated {
+		t.Fatalf("follow got status %d, %s", status, body)
+	}
+	status, body = ts.api(t, token, http.MethodGet, "follows", "")
+	follows := apiPage[Follow](t, status, body).Items
+	if len(follows) != 1 || follows[0].FeedID != feed.ID || follows[0].Category != "News" {
+		t.Errorf("follows are %+v, want %s in News", follows, feed.ID)
+	}
+
+	tests := []struct {
+		name	string
+		body	string
+		status	int
+	}{
+		{name: "again", body: `{"url": "https://example.com"}`, status: http.StatusConflict},
+		{name: "unknown feed", body: `{"url": "https://unknown.example.com"}`, status: http.StatusNotFound},
+		{name: "no url", body: `{}`, status: http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		status, body := ts.api(t, token, http.MethodPost, "follows", test.body)
+		if status != test.status {
+			t.Errorf("follow %s: status %d, want %d (%s)", test.name, status, test.status, body)
+		}
+	}
+
+	status, _ = ts.api(t, token, http.MethodDelete, "follows/" + feed.ID.String(), "")
+	if status != http.StatusNoContent {
+		t.Errorf("unfollow got status %d, want %d", status, http.StatusNoContent)
+	}
+	status, body = ts.api(t, token, http.MethodGet, "follows", "")
+	if follows := apiPage[Follow](t, status, body).Items; len(follows) != 0 {
+		t.Errorf("follows after unfollow are %+v, want none", follows)
+	}
+	status, _ = ts.api(t, token, http.MethodDelete, "follows/not-a-uuid", "")
+	if status != http.StatusBadRequest {
+		t.Errorf("unfollow of a bad id got status %d, want %d", status, http.StatusBadRequest)
+	}
+}
+
+// failingFollows is a store whose follows cannot be written
+type failingFollows struct {
+	store.Store
+}
+
+func (failingFollows) CreateFeedFollows(context.Context, database.CreateFeedFollowsParams) (database.CreateFeedFollowsRow, error) {
+	return database.CreateFeedFollowsRow{}, errors.New("connection reset by peer")
+}
+
+func TestAPIInternalError(t *testing.T) {
+	ts := newTestServer(t)
+	owner, _ := ts.addUser(t, "owner")
+	ts.addFeed(t, owner, "https://example.com", "")
+	_, token := ts.addUser(t, "gator")
+	ts.state.Db = failingFollows{ts.state.Db}
+
+	status, body := ts.api(t, token, http.MethodPost, "follows", `{"url": "https://example.com"}`)
+	if status != http.StatusInternalServerError {
+		t.Errorf("follow got status %d, want %d", status, http.StatusInternalServerError)
+	}
+	if strings.Contains(body, "connection reset") {
+		t.Errorf("response %s leaks the database error", body)
+	}
+}
@@ -0,0 +1,324 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theMagicRabbit/gator/internal/database"
+)
+
+// feverItemLimit is the most items the Fever API returns per request
+const feverItemLimit = 50
+
+type feverGroup struct {
+	ID		int64	`json:"id"`
+	Title	string	`json:"title"`
+}
+
+type feverFeedsGroup struct {
+	GroupID	int64	`json:"group_id"`
+	FeedIDs	string	`json:"feed_ids"`
+}
+
+type feverFeed struct {
+	ID					int64	`json:"id"`
+	FaviconID			int64	`json:"favicon_id"`
+	Title				string	`json:"title"`
+	URL					string	`json:"url"`
+	SiteURL				string	`json:"site_url"`
+	IsSpark				int		`json:"is_spark"`
+	LastUpdatedOnTime	int64	`json:"last_updated_on_time"`
+}
+
+type feverItem struct {
+	ID				int64	`json:"id"`
+	FeedID			int64	`json:"feed_id"`
+	Title			string	`json:"title"`
+	Author			string	`json:"author"`
+	HTML			string	`json:"html"`
+	URL				string	`json:"url"`
+	IsSaved			int		`json:"is_saved"`
+	IsRead			int		`json:"is_read"`
+	CreatedOnTime	int64	`json:"created_on_time"`
+}
+
+// feverGroupID derives a stable group id from a category name. Fever
+// clients cache group ids, so they must not change as categories come and go.
+func feverGroupID(category string) int64 {
+	return int64(crc32.ChecksumIEEE([]byte(category))&0x7fffffff) + 1
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func joinIDs(ids []int64) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.FormatInt(id, 10))
+	}
+	return strings.Join(parts, ",")
+}
+
+// feverGroups builds the groups and feed to group mapping from the
+// categories of the user's follows.
+func feverGroups(feeds []database.GetFeverFeedsForUserRow) ([]feverGroup, []feverFeedsGroup) {
+	members := map[string][]int64{}
+	for _, f := range feeds {
+		if f.Category.Valid {
+			members[f.Category.String] = append(members[f.Category.String], f.SerialID)
+		}
+	}
+	categories := make([]string, 0, len(members))
+	for category := range members {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	groups := []feverGroup{}
+	feedsGroups := []feverFeedsGroup{}
+	for _, category := range categories {
+		id := feverGroupID(category)
+		groups = append(groups, feverGroup{ID: id, Title: category})
+		feedsGroups = append(feedsGroups, feverFeedsGroup{GroupID: id, FeedIDs: joinIDs(members[category])})
+	}
+	return groups, feedsGroups
+}
+
+// handleFever implements the Fever API. Clients authenticate with
+// md5("name:token") for an API token created by gator token.
+func (srv *Server) handleFever(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response := map[string]any{
+		"api_version": 3,
+		"auth": 0,
+	}
+	apiKey := strings.ToLower(r.Form.Get("api_key"))
+	user, err := srv.state.Db.GetUserFromFeverKey(r.Context(), sql.NullString{String: apiKey, Valid: apiKey != ""})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithJSON(w, http.StatusOK, response)
+		return
+	} else if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response["auth"] = 1
+
+	feeds, err := srv.state.Db.GetFeverFeedsForUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var lastRefreshed int64
+	for _, f := range feeds {
+		if f.LastFetchedAt.Valid {
+			lastRefreshed = max(lastRefreshed, f.LastFetchedAt.Time.Unix())
+		}
+	}
+	response["last_refreshed_on_time"] = lastRefreshed
+
+	if r.Form.Has("mark") {
+		err = srv.feverMark(r, user, feeds)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if r.Form.Has("groups") || r.Form.Has("feeds") {
+		groups, feedsGroups := feverGroups(feeds)
+		if r.Form.Has("groups") {
+			response["groups"] = groups
+		}
+		response["feeds_groups"] = feedsGroups
+	}
+	if r.Form.Has("feeds") {
+		feverFeeds := make([]feverFeed, 0, len(feeds))
+		for _, f := range feeds {
+			var updated int64
+			if f.LastFetchedAt.Valid {
+				updated = f.LastFetchedAt.Time.Unix()
+			}
+			feverFeeds = append(feverFeeds, feverFeed{
+				ID: f.SerialID,
+				Title: f.Name,
+				URL: f.Url,
+				SiteURL: f.Url,
+				LastUpdatedOnTime: updated,
+			})
+		}
+		response["feeds"] = feverFeeds
+	}
+	if r.Form.Has("favicons") {
+		response["favicons"] = []any{}
+	}
+	if r.Form.Has("links") {
+		response["links"] = []any{}
+	}
+	if r.Form.Has("items") {
+		items, total, err := srv.feverItems(r, user)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		response["items"] = items
+		response["total_items"] = total
+	}
+	if r.Form.Has("unread_item_ids") {
+		ids, err := srv.state.Db.GetUnreadPostSerialIDsForUser(r.Context(), user.ID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		response["unread_item_ids"] = joinIDs(ids)
+	}
+	if r.Form.Has("saved_item_ids") {
+		ids, err := srv.state.Db.GetStarredPostSerialIDsForUser(r.Context(), user.ID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		response["saved_item_ids"] = joinIDs(ids)
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// feverItems returns up to feverItemLimit items selected by the since_id,
+// max_id or with_ids parameters, along with the user's total item count.
+func (srv *Server) feverItems(r *http.Request, user database.User) ([]feverItem, int64, error) {
+	params := database.GetFeverItemsForUserParams{
+		UserID: user.ID,
+		ItemLimit: feverItemLimit,
+	}
+	if value := r.Form.Get("with_ids"); value != "" {
+		for _, part := range strings.Split(value, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+			if err != nil {
+				return nil, 0, err
+			}
+			params.WithIds = append(params.WithIds, id)
+		}
+	} else if value := r.Form.Get("max_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, 0, err
+		}
+		params.MaxID = sql.NullInt64{Int64: id, Valid: true}
+		params.NewestFirst = true
+	} else {
+		id, _ := strconv.ParseInt(r.Form.Get("since_id"), 10, 64)
+		params.SinceID = sql.NullInt64{Int64: id, Valid: true}
+	}
+	rows, err := srv.state.Db.GetFeverItemsForUser(r.Context(), params)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := srv.state.Db.CountPostsForUser(r.Context(), user.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+	items := make([]feverItem, 0, len(rows))
+	for _, row := range rows {
+		created := row.CreatedAt
+		if row.PublishedAt.Valid {
+			created = row.PublishedAt.Time
+		}
+		items = append(items, feverItem{
+			ID: row.SerialID,
+			FeedID: row.FeedSerialID,
+			Title: row.Title.String,
+			Author: row.Author.String,
+			HTML: row.Description.String,
+			URL: row.Url.String,
+			IsSaved: boolInt(row.IsSaved),
+			IsRead: boolInt(row.IsRead),
+			CreatedOnTime: created.Unix(),
+		})
+	}
+	return items, total, nil
+}
+
+// feverMark applies a mark action: an item as read, unread, saved or unsaved,
+// or every item of a feed or group as read up to the before timestamp.
+func (srv *Server) feverMark(r *http.Request, user database.User, feeds []database.GetFeverFeedsForUserRow) error {
+	id, err := strconv.ParseInt(r.Form.Get("id"), 10, 64)
+	if err != nil {
+		return err
+	}
+	as := r.Form.Get("as")
+	utcNow := time.Now().UTC()
+	switch r.Form.Get("mark") {
+	case "item":
+		// Only items of feeds the user follows can be marked
+		rows, err := srv.state.Db.GetFeverItemsForUser(r.Context(), database.GetFeverItemsForUserParams{
+			UserID: user.ID,
+			WithIds: []int64{id},
+			ItemLimit: 1,
+		})
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return fmt.Errorf("no item %d in your feeds", id)
+		}
+		post := rows[0]
+		switch as {
+		case "read":
+			return srv.state.Db.MarkPostRead(r.Context(), database.MarkPostReadParams{UserID: user.ID, PostID: post.ID, ReadAt: utcNow})
+		case "unread":
+			return srv.state.Db.MarkPostUnread(r.Context(), database.MarkPostUnreadParams{UserID: user.ID, PostID: post.ID})
+		case "saved":
+			return srv.state.Db.StarPost(r.Context(), database.StarPostParams{UserID: user.ID, PostID: post.ID, StarredAt: utcNow})
+		case "unsaved":
+			return srv.state.Db.UnstarPost(r.Context(), database.UnstarPostParams{UserID: user.ID, PostID: post.ID})
+		}
+		return errors.New("unknown item mark")
+	case "feed", "group":
+		if as != "read" {
+			return errors.New("feeds and groups can only be marked read")
+		}
+		before := utcNow
+		if value := r.Form.Get("before"); value != "" {
+			unix, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return err
+			}
+			before = time.Unix(unix, 0).UTC()
+		}
+		var feedIDs []uuid.UUID
+		for _, f := range feeds {
+			inFeed := r.Form.Get("mark") == "feed" && f.SerialID == id
+			// Group 0 is the Kindling super group of every feed
+			inGroup := r.Form.Get("mark") == "group" && (id == 0 || (f.Category.Valid && feverGroupID(f.Category.String) == id))
+			if inFeed || inGroup {
+				feedIDs = append(feedIDs, f.ID)
+			}
+		}
+		for _, feedID := range feedIDs {
+			err = srv.state.Db.MarkFeedReadBefore(r.Context(), database.MarkFeedReadBeforeParams{
+				UserID: user.ID,
+				ReadAt: utcNow,
+				FeedID: feedID,
+				CreatedBefore: before,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return errors.New("unknown mark")
+}
@@ -0,0 +1,226 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/theMagicRabbit/gator/internal/auth"
+	"github.com/theMagicRabbit/gator/internal/database"
+)
+
+type feverResponse struct {
+	Auth			int					`json:"auth"`
+	Groups			[]feverGroup		`json:"groups"`
+	FeedsGroups		[]feverFeedsGroup	`json:"feeds_groups"`
+	Feeds			[]feverFeed			`json:"feeds"`
+	Items			[]feverItem			`json:"items"`
+	TotalItems		int64				`json:"total_items"`
+	UnreadItemIDs	string				`json:"unread_item_ids"`
+	SavedItemIDs	string				`json:"saved_item_ids"`
+	Error			string				`json:"error"`
+}
+
+// fever posts a Fever API request as the user with the given token. The
+// query selects the data wanted, the form carries the action, as clients do.
+func (ts *testServer) fever(t *testing.T, name, token, query string, form url.Values) (int, feverResponse) {
+	t.Helper()
+	if form == nil {
+		form = url.Values{}
+	}
+	form.Set("api_key", auth.FeverKey(name, token))
+	res, err := http.PostForm(ts.URL + "/fever/?api&" + query, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var body feverResponse
+	err = json.NewDecoder(res.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.StatusCode, body
+}
+
+func itemIDs(items []feverItem) []int64 {
+	ids := []int64{}
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestFeverAuth(t *testing.T) {
+	ts := newTestServer(t)
+	_, token := ts.addUser(t, "gator")
+
+	_, body := ts.fever(t, "gator", "wrong", "feeds", nil)
+	if body.Auth != 0 || body.Feeds != nil {
+		t.Errorf("wrong key got auth %d and feeds %v, want 0 and none", body.Auth, body.Feeds)
+	}
+	_, body = ts.fever(t, "other", token, "feeds", nil)
+	if body.Auth != 0 {
+		t.Errorf("key for another name got auth %d, want 0", body.Auth)
+	}
+	_, body = ts.fever(t, "gator", token, "feeds", nil)
+	if body.Auth != 1 || body.Feeds == nil {
+		t.Errorf("right key got auth %d and feeds %v, want 1 and a list", body.Auth, body.Feeds)
+	}
+}
+
+func TestFeverGroupsAndFeeds(t *testing.T) {
+	ts := newTestServer(t)
+	user, token := ts.addUser(t, "gator")
+	news := ts.addFeed(t, user, "https://news.example.com", "News")
+	blog := ts.addFeed(t, user, "https://blog.example.com", "")
+
+	_, body := ts.fever(t, "gator", token, "groups&feeds", nil)
+	wantGroups := []feverGroup{{ID: feverGroupID("News"), Title: "News"}}
+	if !slices.Equal(body.Groups, wantGroups) {
+		t.Errorf("groups are %+v, want %+v", body.Groups, wantGroups)
+	}
+	wantFeedsGroups := []feverFeedsGroup{{GroupID: feverGroupID("News"), FeedIDs: strconv.FormatInt(news.SerialID, 10)}}
+	if !slices.Equal(body.FeedsGroups, wantFeedsGroups) {
+		t.Errorf("feeds_groups are %+v, want %+v", body.FeedsGroups, wantFeedsGroups)
+	}
+	var feedIDs []int64
+	for _, f := range body.Feeds {
+		feedIDs = append(feedIDs, f.ID)
+	}
+	slices.Sort(feedIDs)
+	if want := []int64{news.SerialID, blog.SerialID}; !slices.Equal(feedIDs, want) {
+		t.Errorf("feeds are %v, want %v", feedIDs, want)
+	}
+}
+
+func TestFeverItems(t *testing.T) {
+	ts := newTestServer(t)
+	user, token := ts.addUser(t, "gator")
+	other, _ := ts.addUser(t, "other")
+	feed := ts.addFeed(t, user, "https://example.com", "")
+	hidden := ts.addFeed(t, other, "https://hidden.example.com", "")
+	first := ts.addPost(t, feed, "first", 3*time.Hour)
+	ts.addPost(t, hidden, "hidden", 2*time.Hour)
+	second := ts.addPost(t, feed, "second", time.Hour)
+	third := ts.addPost(t, feed, "third", 0)
+
+	tests := []struct {
+		query	string
+		want	[]int64
+	}{
+		{query: "items", want: []int64{first.SerialID, second.SerialID, third.SerialID}},
+		{query: fmt.Sprintf("items&since_id=%d", first.SerialID), want: []int64{second.SerialID, third.SerialID}},
+		{query: fmt.Sprintf("items&max_id=%d", third.SerialID), want: []int64{second.SerialID, first.SerialID}},
+		{query: fmt.Sprintf("items&with_ids=%d,%d", third.SerialID, first.SerialID), want: []int64{first.SerialID, third.SerialID}},
+		{query: fmt.Sprintf("items&with_ids=%d", first.SerialID + 1), want: []int64{}},
+	}
+	for _, test := range tests {
+		status, body := ts.fever(t, "gator", token, test.query, nil)
+		if status != http.StatusOK {
+			t.Errorf("%s: status %d, %s", test.query, status, body.Error)
+			continue
+		}
+		if got := itemIDs(body.Items); !slices.Equal(got, test.want) {
+			t.Errorf("%s: items are %v, want %v", test.query, got, test.want)
+		}
+		if body.TotalItems != 3 {
+			t.Errorf("%s: total_items is %d, want 3", test.query, body.TotalItems)
+		}
+	}
+
+	_, body := ts.fever(t, "gator", token, fmt.Sprintf("items&with_ids=%d", first.SerialID), nil)
+	want := feverItem{
+		ID: first.SerialID,
+		FeedID: feed.SerialID,
+		Title: "first",
+		Author: "Jane",
+		URL: "https://example.com/first",
+		CreatedOnTime: first.PublishedAt.Time.Unix(),
+	}
+	if len(body.Items) != 1 || body.Items[0] != want {
+		t.Errorf("item is %+v, want %+v", body.Items, want)
+	}
+
+	status, _ := ts.fever(t, "gator", token, "items&with_ids=1,x", nil)
+	if status != http.StatusBadRequest {
+		t.Errorf("bad with_ids got status %d, want %d", status, http.StatusBadRequest)
+	}
+}
+
+func TestFeverMark(t *testing.T) {
+	ts := newTestServer(t)
+	user, token := ts.addUser(t, "gator")
+	other, _ := ts.addUser(t, "other")
+	news := ts.addFeed(t, user, "https://news.example.com", "News")
+	blog := ts.addFeed(t, user, "https://blog.example.com", "")
+	hidden := ts.addFeed(t, other, "https://hidden.example.com", "")
+	headline := ts.addPost(t, news, "headline", time.Hour)
+	entry := ts.addPost(t, blog, "entry", time.Hour)
+	secret := ts.addPost(t, hidden, "secret", time.Hour)
+	ids := func(posts ...int64) string {
+		return joinIDs(posts)
+	}
+	mark := func(values ...string) (int, feverResponse) {
+		form := url.Values{}
+		for i := 0; i < len(values); i += 2 {
+			form.Set(values[i], values[i+1])
+		}
+		return ts.fever(t, "gator", token, "unread_item_ids&saved_item_ids", form)
+	}
+
+	_, body := mark("mark", "item", "as", "read", "id", strconv.FormatInt(headline.SerialID, 10))
+	if body.UnreadItemIDs != ids(entry.SerialID) {
+		t.Errorf("after reading headline unread is %q, want %q", body.UnreadItemIDs, ids(entry.SerialID))
+	}
+	_, body = mark("mark", "item", "as", "saved", "id", strconv.FormatInt(entry.SerialID, 10))
+	if body.SavedItemIDs != ids(entry.SerialID) {
+		t.Errorf("after saving entry saved is %q, want %q", body.SavedItemIDs, ids(entry.SerialID))
+	}
+	_, body = mark("mark", "item", "as", "unsaved", "id", strconv.FormatInt(entry.SerialID, 10))
+	if body.SavedItemIDs != "" {
+		t.Errorf("after unsaving entry saved is %q, want none", body.SavedItemIDs)
+	}
+	_, body = mark("mark", "item", "as", "unread", "id", strconv.FormatInt(headline.SerialID, 10))
+	if body.UnreadItemIDs != ids(headline.SerialID, entry.SerialID) {
+		t.Errorf("after unreading headline unread is %q, want both", body.UnreadItemIDs)
+	}
+
+	// Items outside the user's feeds are rejected and left alone
+	for _, as := range []string{"read", "saved"} {
+		status, _ := mark("mark", "item", "as", as, "id", strconv.FormatInt(secret.SerialID, 10))
+		if status != http.StatusBadRequest {
+			t.Errorf("marking another user's item %s got status %d, want %d", as, status, http.StatusBadRequest)
+		}
+	}
+	starred, err := ts.state.Db.GetStarredPostsForUser(t.Context(), database.GetStarredPostsForUserParams{UserID: user.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(starred) != 0 {
+		t.Errorf("%s starred %d posts of feeds they do not follow", user.Name, len(starred))
+	}
+
+	// Posts stored after before are left unread
+	past := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	_, body = mark("mark", "feed", "as", "read", "id", strconv.FormatInt(blog.SerialID, 10), "before", past)
+	if body.UnreadItemIDs != ids(headline.SerialID, entry.SerialID) {
+		t.Errorf("marking before the posts unread is %q, want both", body.UnreadItemIDs)
+	}
+	_, body = mark("mark", "feed", "as", "read", "id", strconv.FormatInt(blog.SerialID, 10))
+	if body.UnreadItemIDs != ids(headline.SerialID) {
+		t.Errorf("after reading blog unread is %q, want %q", body.UnreadItemIDs, ids(headline.SerialID))
+	}
+	_, body = mark("mark", "group", "as", "read", "id", strconv.FormatInt(feverGroupID("News"), 10))
+	if body.UnreadItemIDs != "" {
+		t.Errorf("after reading News unread is %q, want none", body.UnreadItemIDs)
+	}
+	status, _ := mark("mark", "feed", "as", "unread", "id", strconv.FormatInt(blog.SerialID, 10))
+	if status != http.StatusBadRequest {
+		t.Errorf("marking a feed unread got status %d, want %d", status, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,581 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theMagicRabbit/gator/internal/auth"
+	"github.com/theMagicRabbit/gator/internal/database"
+)
+
+// Google Reader stream and tag IDs
+const (
+	readerReadingList = "user/-/state/com.google/reading-list"
+	readerRead = "user/-/state/com.google/read"
+	readerStarred = "user/-/state/com.google/starred"
+	readerKeptUnread = "user/-/state/com.google/kept-unread"
+	readerLabelPrefix = "user/-/label/"
+	readerFeedPrefix = "feed/"
+	readerItemPrefix = "tag:google.com,2005:reader/item/"
+)
+
+// Page sizes for stream requests
+const (
+	defaultReaderCount = 20
+	maxReaderCount = 1000
+)
+
+type readerCategory struct {
+	ID		string	`json:"id"`
+	Label	string	`json:"label"`
+}
+
+type readerSubscription struct {
+	ID			string				`json:"id"`
+	Title		string				`json:"title"`
+	Categories	[]readerCategory	`json:"categories"`
+	URL			string				`json:"url"`
+	HTMLURL		string				`json:"htmlUrl"`
+	IconURL		string				`json:"iconUrl"`
+}
+
+type readerTag struct {
+	ID		string	`json:"id"`
+	Type	string	`json:"type,omitempty"`
+}
+
+type readerLink struct {
+	Href	string	`json:"href"`
+	Type	string	`json:"type,omitempty"`
+}
+
+type readerContent struct {
+	Direction	string	`json:"direction"`
+	Content		string	`json:"content"`
+}
+
+type readerOrigin struct {
+	StreamID	string	`json:"streamId"`
+	Title		string	`json:"title"`
+	HTMLURL		string	`json:"htmlUrl"`
+}
+
+type readerItem struct {
+	ID				string			`json:"id"`
+	CrawlTimeMsec	string			`json:"crawlTimeMsec"`
+	TimestampUsec	string			`json:"timestampUsec"`
+	Published		int64			`json:"published"`
+	Updated			int64			`json:"updated"`
+	Title			string			`json:"title"`
+	Author			string			`json:"author"`
+	Canonical		[]readerLink	`json:"canonical"`
+	Alternate		[]readerLink	`json:"alternate"`
+	Summary			readerContent	`json:"summary"`
+	Categories		[]string		`json:"categories"`
+	Origin			readerOrigin	`json:"origin"`
+}
+
+type readerItemRef struct {
+	ID				string		`json:"id"`
+	DirectStreamIDs	[]string	`json:"directStreamIds"`
+	TimestampUsec	string		`json:"timestampUsec"`
+}
+
+// registerGoogleReader adds the Google Reader API under /greader. Clients
+// sign in through ClientLogin with a user name and an API token.
+func (srv *Server) registerGoogleReader(mux *http.ServeMux) {
+	mux.HandleFunc("/greader/accounts/ClientLogin", srv.handleReaderClientLogin)
+	mux.HandleFunc("GET /greader/reader/api/0/token", srv.requireGoogleLogin(srv.handleReaderToken))
+	mux.HandleFunc("GET /greader/reader/api/0/user-info", srv.requireGoogleLogin(srv.handleReaderUserInfo))
+	mux.HandleFunc("GET /greader/reader/api/0/subscription/list", srv.requireGoogleLogin(srv.handleReaderSubscriptions))
+	mux.HandleFunc("GET /greader/reader/api/0/tag/list", srv.requireGoogleLogin(srv.handleReaderTags))
+	mux.HandleFunc("/greader/reader/api/0/stream/contents/{streamID...}", srv.requireGoogleLogin(srv.handleReaderStreamContents))
+	mux.HandleFunc("/greader/reader/api/0/stream/items/ids", srv.requireGoogleLogin(srv.handleReaderStreamItemIDs))
+	mux.HandleFunc("POST /greader/reader/api/0/stream/items/contents", srv.requireGoogleLogin(srv.handleReaderItemContents))
+	mux.HandleFunc("POST /greader/reader/api/0/edit-tag", srv.requireGoogleLogin(srv.handleReaderEditTag))
+	mux.HandleFunc("POST /greader/reader/api/0/mark-all-as-read", srv.requireGoogleLogin(srv.handleReaderMarkAllRead))
+}
+
+// requireGoogleLogin authenticates a request by the GoogleLogin auth header
+// handed out by ClientLogin, which is the user's API token.
+func (srv *Server) requireGoogleLogin(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "GoogleLogin auth=")
+		if !ok || token == "" {
+			respondWithText(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		user, err := srv.state.Db.GetUserFromAPIToken(r.Context(), auth.HashToken(token))
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithText(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		} else if err != nil {
+			respondWithText(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		err = r.ParseForm()
+		if err != nil {
+			respondWithText(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx := context.WithValue(r.Context(), userContextKey{}, user)
+		handler(w, r.WithContext(ctx))
+	}
+}
+
+func (srv *Server) handleReaderClientLogin(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		respondWithText(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	name := r.Form.Get("Email")
+	token := r.Form.Get("Passwd")
+	user, err := srv.state.Db.GetUserFromAPIToken(r.Context(), auth.HashToken(token))
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && user.Name != name) {
+		respondWithText(w, http.StatusUnauthorized, "Error=BadAuthentication\n")
+		return
+	} else if err != nil {
+		respondWithText(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondWithText(w, http.StatusOK, fmt.Sprintf("SID=%s\nLSID=null\nAuth=%s\n", token, token))
+}
+
+// handleReaderToken returns the edit token clients send with write requests.
+// Requests are already authenticated by their header, so it is not checked.
+func (srv *Server) handleReaderToken(w http.ResponseWriter, r *http.Request) {
+	user := requestUser(r)
+	respondWithText(w, http.StatusOK, strings.ReplaceAll(user.ID.String(), "-", ""))
+}
+
+func (srv *Server) handleReaderUserInfo(w http.ResponseWriter, r *http.Request) {
+	user := requestUser(r)
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"userId": user.ID.String(),
+		"userName": user.Name,
+		"userProfileId": user.ID.String(),
+		"userEmail": "",
+	})
+}
+
+func (srv *Server) handleReaderSubscriptions(w http.ResponseWriter, r *http.Request) {
+	feeds, err := srv.state.Db.GetFeverFeedsForUser(r.Context(), requestUser(r).ID)
+	if err != nil {
+		respondWithText(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	subscriptions := make([]readerSubscription, 0, len(feeds))
+	for _, f := range feeds {
+		categories := []readerCategory{}
+		if f.Category.Valid {
+			categories = append(categories, readerCategory{ID: readerLabelPrefix + f.Category.String, Label: f.Category.String})
+		}
+		subscriptions = append(subscriptions, readerSubscription{
+			ID: readerFeedID(f.SerialID),
+			Title: f.Name,
+			Categories: categories,
+			URL: f.Url,
+			HTMLURL: f.Url,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, map[string]any{"subscriptions": subscriptions})
+}
+
+func (srv *Server) handleReaderTags(w http.ResponseWriter, r *http.Request) {
+	feeds, err := srv.state.Db.GetFeverFeedsForUser(r.Context(), requestUser(r).ID)
+	if err != nil {
+		respondWithText(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	labels := map[string]bool{}
+	for _, f := range feeds {
+		if f.Category.Valid {
+			labels[f.Category.String] = true
+		}
+	}
+	tags := []readerTag{{ID: readerStarred}}
+	names := make([]string, 0, len(labels))
+	for label := range labels {
+		names = append(names, label)
+	}
+	sort.Strings(names)
+	for _, label := range names {
+		tags = append(tags, readerTag{ID: readerLabelPrefix + label, Type: "folder"})
+	}
+	respondWithJSON(w, http.StatusOK, map[string]any{"tags": tags})
+}
+
+func (srv *Server) handleReaderStreamContents(w http.ResponseWriter, r *http.Request) {
+	streamID := r.PathValue("streamID")
+	if streamID == "" {
+		streamID = r.Form.Get("s")
+	}
+	feeds, err := srv.state.Db.GetFeverFeedsForUser(r.Context(), requestUser(r).ID)
+	if err != nil {
+		respondWithText(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	params, err := readerStreamParams(r, streamID, feeds)
+	if err != nil {
+		respondWithText(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	rows, err := srv.state.Db.GetReaderItemsForUser(r.Context(), params)
+	if err != nil {
+		respondWithText(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response := map[string]any{
+		"direction": "ltr",
+		"id": streamID,
+		"updated": time.Now().Unix(),
+		"items": readerItems(rows, feeds),
+	}
+	if len(rows) == int(params.ItemLimit) {
+		response["continuation"] = strconv.Itoa(int(params.ItemOffset) + len(rows))
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (srv *Server) handleReaderStreamItemIDs(w http.ResponseWriter, r *http.Request) {
+	streamID := r.Form.Get("s")
+	feeds, err := srv.state.Db.GetFeverFeedsForUser(r.Context(), requestUser(r).ID)
+	if err != nil {
+		respondWithText(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	params, err := readerStreamParams(r, streamID, feeds)
+	if err != nil {
+		respondWithText(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	rows, err := srv.state.Db.GetReaderItemsForUser(r.Context(), params)
+	if err != nil {
+		respondWithText(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	refs := make([]readerItemRef, 0, len(rows))
+	for _, row := range rows {
+		refs = append(refs, readerItemRef{
+			ID: strconv.FormatInt(row.SerialID, 10),
+			DirectStreamIDs: []string{},
+			TimestampUsec: strconv.FormatInt(readerItemTime(row).UnixMicro(), 10),
+		})
+	}
+	response := map[string]any{"itemRefs": refs}
+	if len(rows) == int(params.ItemLimit) {
+		response["continuation"] = strconv.Itoa(int(params.ItemOffset) + len(rows))
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (srv *Server) handleReaderItemContents(w http.ResponseWriter, r *http.Request) {
+	user := requestUser(r)
+	ids, err := readerItemIDs(r.Form["i"])
+	if err != nil {
+		respondWithText(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	feeds, err := srv.state.Db.GetFeverFeedsForUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithText(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	rows, err := srv.state.Db.GetReaderItemsForUser(r.Context(), database.GetReaderItemsForUserParams{
+		UserID: user.ID,
+		WithIds: ids,
+		ItemLimit: int32(len(ids)),
+	})
+	if err != nil {
+		respondWithText(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]any{
+		"direction": "ltr",
+		"id": readerReadingList,
+		"updated": time.Now().Unix(),
+		"items": readerItems(rows, feeds),
+	})
+}
+
+// handleReaderEditTag adds and removes the read and starred states of items.
+// Labels belong to feeds in gator, so label edits on items are ignored.
+func (srv *Server) handleReaderEditTag(w http.ResponseWriter, r *http.Request) {
+	user := requestUser(r)
+	ids, err := readerItemIDs(r.Form["i"])
+	if err != nil {
+		respondWithText(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Only items of feeds the user follows can be tagged
+	posts, err := srv.state.Db.GetReaderItemsForUser(r.Context(), database.GetReaderItemsForUserParams{
+		UserID: user.ID,
+		WithIds: ids,
+		ItemLimit: int32(len(ids)),
+	})
+	if err != nil {
+		respondWithText(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for _, id := range ids {
+		found := slices.ContainsFunc(posts, func(post database.GetReaderItemsForUserRow) bool {
+			return post.SerialID == id
+		})
+		if !found {
+			respondWithText(w, http.StatusNotFound, fmt.Sprintf("item %d not found", id))
+			return
+		}
+	}
+	utcNow := time.Now().UTC()
+	for _, post := range posts {
+		for _, tag := range r.Form["a"] {
+			switch readerStreamID(tag) {
+			case readerRead:
+				err = srv.state.Db.MarkPostRead(r.Context(), database.MarkPostReadParams{UserID: user.ID, PostID: post.ID, ReadAt: utcNow})
+			case readerKeptUnread:
+				err = srv.state.Db.MarkPostUnread(r.Context(), database.MarkPostUnreadParams{UserID: user.ID, PostID: post.ID})
+			case readerStarred:
+				err = srv.state.Db.StarPost(r.Context(), database.StarPostParams{UserID: user.ID, PostID: post.ID, StarredAt: utcNow})
+			}
+			if err != nil {
+				respondWithText(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+		for _, tag := range r.Form["r"] {
+			switch readerStreamID(tag) {
+			case readerRead:
+				err = srv.state.Db.MarkPostUnread(r.Context(), database.MarkPostUnreadParams{UserID: user.ID, PostID: post.ID})
+			case readerStarred:
+				err = srv.state.Db.UnstarPost(r.Context(), database.UnstarPostParams{UserID: user.ID, PostID: post.ID})
+			}
+			if err != nil {
+				respondWithText(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+	}
+	respondWithText(w, http.StatusOK, "OK")
+}
+
+// handleReaderMarkAllRead marks every item of a feed, label or the reading
+// list read, up to the ts timestamp in microseconds when one is given.
+func (srv *Server) handleReaderMarkAllRead(w http.ResponseWriter, r *http.Request) {
+	user := requestUser(r)
+	streamID := readerStreamID(r.Form.Get("s"))
+	utcNow := time.Now().UTC()
+	before := utcNow
+	if value := r.Form.Get("ts"); value != "" {
+		usec, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			respondWithText(w, http.StatusBadRequest, "ts must be a timestamp in microseconds")
+			return
+		}
+		before = time.UnixMicro(usec).UTC()
+	}
+	feeds, err := srv.state.Db.GetFeverFeedsForUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithText(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	label, isLabel := strings.CutPrefix(streamID, readerLabelPrefix)
+	var feedIDs []uuid.UUID
+	for _, f := range feeds {
+		inStream := streamID == readerReadingList ||
+			streamID == readerFeedID(f.SerialID) ||
+			(isLabel && f.Category.Valid && f.Category.String == label)
+		if inStream {
+			feedIDs = append(feedIDs, f.ID)
+		}
+	}
+	for _, feedID := range feedIDs {
+		err = srv.state.Db.MarkFeedReadBefore(r.Context(), database.MarkFeedReadBeforeParams{
+			UserID: user.ID,
+			ReadAt: utcNow,
+			FeedID: feedID,
+			CreatedBefore: before,
+		})
+		if err != nil {
+			respondWithText(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	respondWithText(w, http.StatusOK, "OK")
+}
+
+// readerStreamParams turns a stream ID and the n, c, r, ot, nt, xt and it
+// parameters into a query for the user's items.
+func readerStreamParams(r *http.Request, streamID string, feeds []database.GetFeverFeedsForUserRow) (database.GetReaderItemsForUserParams, error) {
+	params := database.GetReaderItemsForUserParams{
+		UserID: requestUser(r).ID,
+		ItemLimit: defaultReaderCount,
+		OldestFirst: r.Form.Get("r") == "o",
+	}
+	streamID = readerStreamID(streamID)
+	switch {
+	case streamID == "" || streamID == readerReadingList:
+	case streamID == readerStarred:
+		params.StarredOnly = true
+	case streamID == readerRead:
+		params.ReadOnly = true
+	case strings.HasPrefix(streamID, readerLabelPrefix):
+		params.Category = sql.NullString{String: strings.TrimPrefix(streamID, readerLabelPrefix), Valid: true}
+	case strings.HasPrefix(streamID, readerFeedPrefix):
+		found := false
+		for _, f := range feeds {
+			if readerFeedID(f.SerialID) == streamID {
+				params.FeedID = uuid.NullUUID{UUID: f.ID, Valid: true}
+				found = true
+			}
+		}
+		if !found {
+			return params, fmt.Errorf("unknown stream %s", streamID)
+		}
+	default:
+		return params, fmt.Errorf("unknown stream %s", streamID)
+	}
+	for _, tag := range r.Form["xt"] {
+		if readerStreamID(tag) == readerRead {
+			params.ExcludeRead = true
+		}
+	}
+	for _, tag := range r.Form["it"] {
+		switch readerStreamID(tag) {
+		case readerRead:
+			params.ReadOnly = true
+		case readerStarred:
+			params.StarredOnly = true
+		}
+	}
+	if value := r.Form.Get("n"); value != "" {
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 1 {
+			return params, errors.New("n must be a positive number")
+		}
+		params.ItemLimit = int32(min(count, maxReaderCount))
+	}
+	if value := r.Form.Get("c"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return params, errors.New("invalid continuation")
+		}
+		params.ItemOffset = int32(offset)
+	}
+	if value := r.Form.Get("ot"); value != "" {
+		unix, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return params, errors.New("ot must be a unix timestamp")
+		}
+		params.NewerThan = sql.NullTime{Time: time.Unix(unix, 0).UTC(), Valid: true}
+	}
+	if value := r.Form.Get("nt"); value != "" {
+		unix, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return params, errors.New("nt must be a unix timestamp")
+		}
+		params.OlderThan = sql.NullTime{Time: time.Unix(unix, 0).UTC(), Valid: true}
+	}
+	return params, nil
+}
+
+// readerStreamID rewrites user/<id>/ stream IDs to the user/-/ form
+func readerStreamID(streamID string) string {
+	parts := strings.SplitN(streamID, "/", 3)
+	if len(parts) == 3 && parts[0] == "user" {
+		return "user/-/" + parts[2]
+	}
+	return streamID
+}
+
+func readerFeedID(serialID int64) string {
+	return readerFeedPrefix + strconv.FormatInt(serialID, 10)
+}
+
+// readerItemIDs parses item IDs in either the long tag form, which holds
+// the ID in hex, or the short decimal form.
+func readerItemIDs(values []string) ([]int64, error) {
+	ids := make([]int64, 0, len(values))
+	for _, value := range values {
+		var id int64
+		var err error
+		if hex, ok := strings.CutPrefix(value, readerItemPrefix); ok {
+			var unsigned uint64
+			unsigned, err = strconv.ParseUint(hex, 16, 64)
+			id = int64(unsigned)
+		} else {
+			id, err = strconv.ParseInt(value, 10, 64)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid item id %s", value)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func readerItemTime(row database.GetReaderItemsForUserRow) time.Time {
+	if row.PublishedAt.Valid {
+		return row.PublishedAt.Time
+	}
+	return row.CreatedAt
+}
+
+func readerItems(rows []database.GetReaderItemsForUserRow, feeds []database.GetFeverFeedsForUserRow) []readerItem {
+	feedsBySerial := map[int64]database.GetFeverFeedsForUserRow{}
+	for _, f := range feeds {
+		feedsBySerial[f.SerialID] = f
+	}
+	items := make([]readerItem, 0, len(rows))
+	for _, row := range rows {
+		f := feedsBySerial[row.FeedSerialID]
+		categories := []string{readerReadingList}
+		if row.IsRead {
+			categories = append(categories, readerRead)
+		}
+		if row.IsStarred {
+			categories = append(categories, readerStarred)
+		}
+		if f.Category.Valid {
+			categories = append(categories, readerLabelPrefix + f.Category.String)
+		}
+		// The item's own categories are sent as plain strings, as Google
+		// Reader did, so clients do not mistake them for user labels
+		categories = append(categories, row.Categories...)
+		published := readerItemTime(row)
+		items = append(items, readerItem{
+			ID: fmt.Sprintf("%s%016x", readerItemPrefix, row.SerialID),
+			CrawlTimeMsec: strconv.FormatInt(row.CreatedAt.UnixMilli(), 10),
+			TimestampUsec: strconv.FormatInt(published.UnixMicro(), 10),
+			Published: published.Unix(),
+			Updated: published.Unix(),
+			Title: row.Title.String,
+			Author: row.Author.String,
+			Canonical: []readerLink{{Href: row.Url.String}},
+			Alternate: []readerLink{{Href: row.Url.String, Type: "text/html"}},
+			Summary: readerContent{Direction: "ltr", Content: row.Description.String},
+			Categories: categories,
+			Origin: readerOrigin{
+				StreamID: readerFeedID(row.FeedSerialID),
+				Title: f.Name,
+				HTMLURL: f.Url,
+			},
+		})
+	}
+	return items
+}
+
+func respondWithText(w http.ResponseWriter, code int, text string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(code)
+	w.Write([]byte(text))
+}
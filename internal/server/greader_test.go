@@ -0,0 +1,311 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/theMagicRabbit/gator/internal/database"
+)
+
+// reader sends a Google Reader API request signed in with the token. GET
+// requests carry the form in the query, POST requests in the body.
+func (ts *testServer) reader(t *testing.T, token, method, path string, form url.Values) (int, string) {
+	t.Helper()
+	target := ts.URL + "/greader/reader/api/0/" + path
+	var body io.Reader
+	if method == http.MethodGet {
+		target += "?" + form.Encode()
+	} else {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if token != "" {
+		req.Header.Set("Authorization", "GoogleLogin auth=" + token)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.StatusCode, string(data)
+}
+
+// readerStream returns the items of a stream
+func (ts *testServer) readerStream(t *testing.T, token string, form url.Values) ([]readerItem, string) {
+	t.Helper()
+	status, body := ts.reader(t, token, http.MethodGet, "stream/contents/", form)
+	if status != http.StatusOK {
+		t.Fatalf("stream %v: status %d, %s", form, status, body)
+	}
+	var stream struct {
+		Items			[]readerItem	`json:"items"`
+		Continuation	string			`json:"continuation"`
+	}
+	err := json.Unmarshal([]byte(body), &stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stream.Items, stream.Continuation
+}
+
+func readerTitles(items []readerItem) []string {
+	titles := []string{}
+	for _, item := range items {
+		titles = append(titles, item.Title)
+	}
+	return titles
+}
+
+func TestReaderClientLogin(t *testing.T) {
+	ts := newTestServer(t)
+	_, token := ts.addUser(t, "gator")
+	ts.addUser(t, "other")
+
+	tests := []struct {
+		name	string
+		email	string
+		passwd	string
+		status	int
+	}{
+		{name: "token", email: "gator", passwd: token, status: http.StatusOK},
+		{name: "wrong token", email: "gator", passwd: "wrong", status: http.StatusUnauthorized},
+		{name: "another user", email: "other", passwd: token, status: http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		res, err := http.PostForm(ts.URL + "/greader/accounts/ClientLogin", url.Values{"Email": {test.email}, "Passwd": {test.passwd}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != test.status {
+			t.Errorf("%s: status %d, want %d", test.name, res.StatusCode, test.status)
+		}
+		if test.status == http.StatusOK && !strings.Contains(string(body), "Auth=" + token + "\n") {
+			t.Errorf("%s: response %q has no Auth line", test.name, body)
+		}
+	}
+
+	for _, auth := range []string{"", "wrong"} {
+		status, _ := ts.reader(t, auth, http.MethodGet, "user-info", url.Values{})
+		if status != http.StatusUnauthorized {
+			t.Errorf("user-info with auth %q got status %d, want %d", auth, status, http.StatusUnauthorized)
+		}
+	}
+	status, body := ts.reader(t, token, http.MethodGet, "user-info", url.Values{})
+	if status != http.StatusOK || !strings.Contains(body, `"userName":"gator"`) {
+		t.Errorf("user-info got status %d and %s", status, body)
+	}
+}
+
+func TestReaderSubscriptionsAndTags(t *testing.T) {
+	ts := newTestServer(t)
+	user, token := ts.addUser(t, "gator")
+	news := ts.addFeed(t, user, "https://news.example.com", "News")
+	blog := ts.addFeed(t, user, "https://blog.example.com", "")
+
+	_, body := ts.reader(t, token, http.MethodGet, "subscription/list", url.Values{"output": {"json"}})
+	var list struct {
+		Subscriptions	[]readerSubscription	`json:"subscriptions"`
+	}
+	err := json.Unmarshal([]byte(body), &list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string][]readerCategory{}
+	for _, sub := range list.Subscriptions {
+		got[sub.ID] = sub.Categories
+	}
+	want := map[string][]readerCategory{
+		readerFeedID(news.SerialID): {{ID: readerLabelPrefix + "News", Label: "News"}},
+		readerFeedID(blog.SerialID): {},
+	}
+	if len(got) != len(want) {
+		t.Errorf("subscriptions are %+v, want %+v", got, want)
+	}
+	for id, categories := range want {
+		if !slices.Equal(got[id], categories) {
+			t.Errorf("subscription %s has categories %+v, want %+v", id, got[id], categories)
+		}
+	}
+
+	_, body = ts.reader(t, token, http.MethodGet, "tag/list", url.Values{"output": {"json"}})
+	var tags struct {
+		Tags	[]readerTag	`json:"tags"`
+	}
+	err = json.Unmarshal([]byte(body), &tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantTags := []readerTag{{ID: readerStarred}, {ID: readerLabelPrefix + "News", Type: "folder"}}
+	if !slices.Equal(tags.Tags, wantTags) {
+		t.Errorf("tags are %+v, want %+v", tags.Tags, wantTags)
+	}
+}
+
+func TestReaderStreamContents(t *testing.T) {
+	ts := newTestServer(t)
+	user, token := ts.addUser(t, "gator")
+	other, _ := ts.addUser(t, "other")
+	news := ts.addFeed(t, user, "https://news.example.com", "News")
+	blog := ts.addFeed(t, user, "https://blog.example.com", "")
+	hidden := ts.addFeed(t, other, "https://hidden.example.com", "")
+	ts.addPost(t, news, "old news", 3*time.Hour)
+	entry := ts.addPost(t, blog, "entry", 2*time.Hour)
+	ts.addPost(t, hidden, "secret", time.Hour)
+	ts.addPost(t, news, "headline", 0)
+	err := ts.state.Db.MarkPostRead(t.Context(), database.MarkPostReadParams{UserID: user.ID, PostID: entry.ID, ReadAt: time.Now().UTC()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name	string
+		form	url.Values
+		want	[]string
+	}{
+		{name: "reading list", form: url.Values{}, want: []string{"headline", "entry", "old news"}},
+		{name: "oldest first", form: url.Values{"r": {"o"}}, want: []string{"old news", "entry", "headline"}},
+		{name: "feed", form: url.Values{"s": {readerFeedID(blog.SerialID)}}, want: []string{"entry"}},
+		{name: "label", form: url.Values{"s": {"user/1/label/News"}}, want: []string{"headline", "old news"}},
+		{name: "unread", form: url.Values{"xt": {readerRead}}, want: []string{"headline", "old news"}},
+		{name: "read", form: url.Values{"s": {readerRead}}, want: []string{"entry"}},
+		{name: "newer than", form: url.Values{"ot": {strconv.FormatInt(time.Now().Add(-150 * time.Minute).Unix(), 10)}}, want: []string{"headline", "entry"}},
+	}
+	for _, test := range tests {
+		items, _ := ts.readerStream(t, token, test.form)
+		if got := readerTitles(items); !slices.Equal(got, test.want) {
+			t.Errorf("%s: items are %q, want %q", test.name, got, test.want)
+		}
+	}
+
+	items, continuation := ts.readerStream(t, token, url.Values{"n": {"2"}})
+	if got := readerTitles(items); !slices.Equal(got, []string{"headline", "entry"}) || continuation != "2" {
+		t.Errorf("first page is %q continuing at %q, want headline and entry continuing at 2", got, continuation)
+	}
+	items, continuation = ts.readerStream(t, token, url.Values{"n": {"2"}, "c": {continuation}})
+	if got := readerTitles(items); !slices.Equal(got, []string{"old news"}) || continuation != "" {
+		t.Errorf("second page is %q continuing at %q, want old news and no continuation", got, continuation)
+	}
+
+	items, _ = ts.readerStream(t, token, url.Values{"s": {readerFeedID(blog.SerialID)}})
+	if len(items) != 1 {
+		t.Fatalf("blog stream has %d items, want 1", len(items))
+	}
+	item := items[0]
+	wantCategories := []string{readerReadingList, readerRead, "news"}
+	if item.ID != fmt.Sprintf("%s%016x", readerItemPrefix, entry.SerialID) || item.Author != "Jane" || !slices.Equal(item.Categories, wantCategories) {
+		t.Errorf("entry is %s by %q in %q, want serial %d by Jane in %q", item.ID, item.Author, item.Categories, entry.SerialID, wantCategories)
+	}
+
+	status, _ := ts.reader(t, token, http.MethodGet, "stream/contents/", url.Values{"s": {readerFeedID(hidden.SerialID)}})
+	if status != http.StatusBadRequest {
+		t.Errorf("stream of an unfollowed feed got status %d, want %d", status, http.StatusBadRequest)
+	}
+}
+
+func TestReaderEditTag(t *testing.T) {
+	ts := newTestServer(t)
+	user, token := ts.addUser(t, "gator")
+	other, _ := ts.addUser(t, "other")
+	feed := ts.addFeed(t, user, "https://example.com", "")
+	hidden := ts.addFeed(t, other, "https://hidden.example.com", "")
+	first := ts.addPost(t, feed, "first", time.Hour)
+	second := ts.addPost(t, feed, "second", 0)
+	secret := ts.addPost(t, hidden, "secret", 0)
+	longID := func(post database.Post) string {
+		return fmt.Sprintf("%s%016x", readerItemPrefix, post.SerialID)
+	}
+	editTag := func(form url.Values) int {
+		status, _ := ts.reader(t, token, http.MethodPost, "edit-tag", form)
+		return status
+	}
+	stream := func(streamID string) []string {
+		items, _ := ts.readerStream(t, token, url.Values{"s": {streamID}})
+		return readerTitles(items)
+	}
+
+	status := editTag(url.Values{"i": {longID(first), strconv.FormatInt(second.SerialID, 10)}, "a": {readerRead}})
+	if got := stream(readerRead); status != http.StatusOK || !slices.Equal(got, []string{"second", "first"}) {
+		t.Errorf("after reading both, status %d and read items %q", status, got)
+	}
+	editTag(url.Values{"i": {longID(first)}, "a": {"user/-/state/com.google/kept-unread"}})
+	if got := stream(readerRead); !slices.Equal(got, []string{"second"}) {
+		t.Errorf("after keeping first unread, read items are %q", got)
+	}
+	editTag(url.Values{"i": {longID(second)}, "r": {readerRead}, "a": {readerStarred}})
+	if got := stream(readerRead); len(got) != 0 {
+		t.Errorf("after unreading second, read items are %q", got)
+	}
+	if got := stream(readerStarred); !slices.Equal(got, []string{"second"}) {
+		t.Errorf("after starring second, starred items are %q", got)
+	}
+	editTag(url.Values{"i": {longID(second)}, "r": {readerStarred}})
+	if got := stream(readerStarred); len(got) != 0 {
+		t.Errorf("after unstarring second, starred items are %q", got)
+	}
+
+	// Items outside the user's feeds are rejected, and so is the whole edit
+	status = editTag(url.Values{"i": {longID(first), longID(secret)}, "a": {readerStarred}})
+	if status != http.StatusNotFound {
+		t.Errorf("starring another user's item got status %d, want %d", status, http.StatusNotFound)
+	}
+	starred, err := ts.state.Db.GetStarredPostsForUser(t.Context(), database.GetStarredPostsForUserParams{UserID: user.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(starred) != 0 {
+		t.Errorf("%s starred %d posts after a rejected edit", user.Name, len(starred))
+	}
+	status = editTag(url.Values{"i": {"not an id"}, "a": {readerRead}})
+	if status != http.StatusBadRequest {
+		t.Errorf("bad item id got status %d, want %d", status, http.StatusBadRequest)
+	}
+}
+
+func TestReaderMarkAllRead(t *testing.T) {
+	ts := newTestServer(t)
+	user, token := ts.addUser(t, "gator")
+	news := ts.addFeed(t, user, "https://news.example.com", "News")
+	blog := ts.addFeed(t, user, "https://blog.example.com", "")
+	ts.addPost(t, news, "headline", 0)
+	ts.addPost(t, blog, "entry", 0)
+	unread := func() []string {
+		items, _ := ts.readerStream(t, token, url.Values{"xt": {readerRead}})
+		return readerTitles(items)
+	}
+
+	past := strconv.FormatInt(time.Now().Add(-time.Hour).UnixMicro(), 10)
+	ts.reader(t, token, http.MethodPost, "mark-all-as-read", url.Values{"s": {readerReadingList}, "ts": {past}})
+	if got := unread(); len(got) != 2 {
+		t.Errorf("marking before the posts left %q unread, want both", got)
+	}
+	ts.reader(t, token, http.MethodPost, "mark-all-as-read", url.Values{"s": {readerLabelPrefix + "News"}})
+	if got := unread(); !slices.Equal(got, []string{"entry"}) {
+		t.Errorf("after reading News, unread items are %q", got)
+	}
+	ts.reader(t, token, http.MethodPost, "mark-all-as-read", url.Values{"s": {readerFeedID(blog.SerialID)}})
+	if got := unread(); len(got) != 0 {
+		t.Errorf("after reading blog, unread items are %q", got)
+	}
+	status, _ := ts.reader(t, token, http.MethodPost, "mark-all-as-read", url.Values{"ts": {"soon"}})
+	if status != http.StatusBadRequest {
+		t.Errorf("bad ts got status %d, want %d", status, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/theMagicRabbit/gator/internal/auth"
+	"github.com/theMagicRabbit/gator/internal/publish"
+)
+
+// handlePublish serves a user's timeline as a feed. The secret in the URL
+// comes from gator publish --secret, so feed readers need no other login.
+func (srv *Server) handlePublish(w http.ResponseWriter, r *http.Request) {
+	user, err := srv.state.Db.GetUserFromPublishToken(r.Context(), auth.HashToken(r.PathValue("secret")))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	format := r.PathValue("format")
+	if format != publish.FormatRSS && format != publish.FormatAtom {
+		http.NotFound(w, r)
+		return
+	}
+	filter := publish.Filter{
+		Category: r.URL.Query().Get("category"),
+		Search: r.URL.Query().Get("q"),
+	}
+	if value := r.URL.Query().Get("limit"); value != "" {
+		filter.Limit, err = strconv.Atoi(value)
+		if err != nil || filter.Limit < 1 || filter.Limit > maxPageSize {
+			http.Error(w, "limit must be between 1 and 100", http.StatusBadRequest)
+			return
+		}
+	}
+	stream, err := publish.ForUser(r.Context(), srv.state, user, filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	stream.Link = scheme + "://" + r.Host + r.URL.RequestURI()
+	w.Header().Set("Content-Type", publish.ContentType(format))
+	stream.Write(w, format)
+}
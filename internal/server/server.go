@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/theMagicRabbit/gator/internal/auth"
+	"github.com/theMagicRabbit/gator/internal/database"
+	"github.com/theMagicRabbit/gator/internal/state"
+)
+
+// Pagination bounds for list endpoints
+const (
+	defaultPageSize = 20
+	maxPageSize = 100
+)
+
+type Server struct {
+	state *state.State;
+}
+
+type userContextKey struct{}
+
+func New(s *state.State) *Server {
+	return &Server{
+		state: s,
+	}
+}
+
+// Handler returns the routes served by gator
+func (srv *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/users", srv.requireToken(srv.handleUsers))
+	mux.HandleFunc("GET /api/me", srv.requireToken(srv.handleMe))
+	mux.HandleFunc("GET /api/feeds", srv.requireToken(srv.handleFeeds))
+	mux.HandleFunc("POST /api/feeds", srv.requireToken(srv.handleCreateFeed))
+	mux.HandleFunc("GET /api/follows", srv.requireToken(srv.handleFollows))
+	mux.HandleFunc("POST /api/follows", srv.requireToken(srv.handleCreateFollow))
+	mux.HandleFunc("DELETE /api/follows/{feedID}", srv.requireToken(srv.handleDeleteFollow))
+	mux.HandleFunc("GET /api/posts", srv.requireToken(srv.handlePosts))
+	mux.HandleFunc("/fever/", srv.handleFever)
+	srv.registerGoogleReader(mux)
+	mux.HandleFunc("GET /publish/{secret}/{format}", srv.handlePublish)
+	srv.registerWeb(mux)
+	return mux
+}
+
+// requireToken authenticates a request by its bearer token and passes the
+// token's user to the handler through the request context.
+func (srv *Server) requireToken(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			respondWithError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+		user, err := srv.state.Db.GetUserFromAPIToken(r.Context(), auth.HashToken(token))
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusUnauthorized, "invalid token")
+			return
+		} else if err != nil {
+			respondWithInternalError(w, r, err)
+			return
+		}
+		ctx := context.WithValue(r.Context(), userContextKey{}, user)
+		handler(w, r.WithContext(ctx))
+	}
+}
+
+// requestUser returns the user authenticated by requireToken
+func requestUser(r *http.Request) database.User {
+	user, _ := r.Context().Value(userContextKey{}).(database.User)
+	return user
+}
+
+// pageParams reads the limit and offset query parameters
+func pageParams(r *http.Request) (int, int, error) {
+	limit := defaultPageSize
+	offset := 0
+	var err error
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+	}
+	if value := r.URL.Query().Get("offset"); value != "" {
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("offset must be zero or more")
+		}
+	}
+	return limit, offset, nil
+}
+
+func respondWithJSON(w http.ResponseWriter, code int, payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+func respondWithError(w http.ResponseWriter, code int, msg string) {
+	type errorResponse struct {
+		Error	string	`json:"error"`
+	}
+	respondWithJSON(w, code, errorResponse{Error: msg})
+}
+
+// respondWithInternalError logs err and sends the client a generic error, so
+// database messages stay out of responses
+func respondWithInternalError(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+	respondWithError(w, http.StatusInternalServerError, "internal server error")
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theMagicRabbit/gator/internal/auth"
+	"github.com/theMagicRabbit/gator/internal/config"
+	"github.com/theMagicRabbit/gator/internal/database"
+	"github.com/theMagicRabbit/gator/internal/state"
+	"github.com/theMagicRabbit/gator/internal/store"
+)
+
+// testServer serves gator's routes from a memory store
+type testServer struct {
+	state	*state.State
+	*httptest.Server
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	s := &state.State{Config: &config.Config{}, Db: store.NewMemory()}
+	server := &testServer{state: s}
+	server.Server = httptest.NewServer(New(s).Handler())
+	t.Cleanup(server.Close)
+	return server
+}
+
+// addUser creates a user with an API token and returns the token
+func (ts *testServer) addUser(t *testing.T, name string) (database.User, string) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().UTC()
+	user, err := ts.state.Db.CreateUser(ctx, database.CreateUserParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ts.state.Db.CreateAPIToken(ctx, database.CreateAPITokenParams{
+		ID: uuid.New(),
+		CreatedAt: now,
+		UserID: user.ID,
+		Name: "test",
+		TokenHash: hash,
+		FeverKey: sql.NullString{String: auth.FeverKey(name, token), Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user, token
+}
+
+// addFeed adds a feed the user follows under a category, or none when the
+// category is empty
+func (ts *testServer) addFeed(t *testing.T, user database.User, url, category string) database.Feed {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().UTC()
+	feed, err := ts.state.Db.CreateFeed(ctx, database.CreateFeedParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: url, Url: url, UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ts.state.Db.CreateFeedFollows(ctx, database.CreateFeedFollowsParams{
+		ID: uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID: user.ID,
+		FeedID: feed.ID,
+		Category: sql.NullString{String: category, Valid: category != ""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return feed
+}
+
+// addPost stores a post in a feed, published the given time ago
+func (ts *testServer) addPost(t *testing.T, feed database.Feed, title string, age time.Duration) database.Post {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().UTC()
+	post, err := ts.state.Db.CreatePost(ctx, database.CreatePostParams{
+		ID: uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Title: sql.NullString{String: title, Valid: true},
+		Url: sql.NullString{String: feed.Url + "/" + title, Valid: true},
+		PublishedAt: sql.NullTime{Time: now.Add(-age), Valid: true},
+		Author: sql.NullString{String: "Jane", Valid: true},
+		Categories: []string{"news"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ts.state.Db.CreatePostFeed(ctx, database.CreatePostFeedParams{PostID: post.ID, FeedID: feed.ID, Guid: title, CreatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return post
+}
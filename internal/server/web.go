@@ -0,0 +1,301 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theMagicRabbit/gator/internal/auth"
+	"github.com/theMagicRabbit/gator/internal/database"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+//go:embed static
+var staticFS embed.FS
+
+const sessionCookie = "gator_session"
+
+// readerPageSize is the number of posts on each page of the web reader
+const readerPageSize = 25
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+	"dec": func(i int) int { return i - 1 },
+}).ParseFS(templateFS, "templates/*.html"))
+
+type loginPage struct {
+	Title	string;
+	Error	string;
+}
+
+type readerPage struct {
+	Title		string;
+	User		database.User;
+	Follows		[]database.GetFeedFollowsForUserRow;
+	Posts		[]database.GetPostsForUserRow;
+	FeedID		string;
+	IncludeRead	bool;
+	Page		int;
+	HasMore		bool;
+}
+
+// PageURL links to another page of the reader, keeping the feed filter
+func (p readerPage) PageURL(page int, includeRead bool) string {
+	query := url.Values{}
+	if p.FeedID != "" {
+		query.Set("feed", p.FeedID)
+	}
+	if includeRead {
+		query.Set("all", "1")
+	}
+	if page > 1 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	if len(query) == 0 {
+		return "/reader"
+	}
+	return "/reader?" + query.Encode()
+}
+
+// registerWeb adds the web reader routes to mux
+func (srv *Server) registerWeb(mux *http.ServeMux) {
+	static, _ := fs.Sub(staticFS, "static")
+	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(static)))
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/reader", http.StatusSeeOther)
+	})
+	mux.HandleFunc("GET /login", srv.handleLoginPage)
+	mux.HandleFunc("POST /login", srv.handleLogin)
+	mux.HandleFunc("POST /logout", srv.handleLogout)
+	mux.HandleFunc("GET /reader", srv.requireSession(srv.handleReader))
+	mux.HandleFunc("POST /reader/posts/{postID}/read", srv.requireSession(srv.handleReaderMarkRead))
+	mux.HandleFunc("POST /reader/posts/{postID}/unread", srv.requireSession(srv.handleReaderMarkUnread))
+	mux.HandleFunc("POST /reader/follow", srv.requireSession(srv.handleReaderFollow))
+	mux.HandleFunc("POST /reader/feeds/{feedID}/unfollow", srv.requireSession(srv.handleReaderUnfollow))
+}
+
+// requireSession authenticates a browser by the API token held in its
+// session cookie, sending unauthenticated visitors to the login page.
+func (srv *Server) requireSession(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(sessionCookie)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		user, err := srv.state.Db.GetUserFromAPIToken(r.Context(), auth.HashToken(cookie.Value))
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ctx := context.WithValue(r.Context(), userContextKey{}, user)
+		handler(w, r.WithContext(ctx))
+	}
+}
+
+func renderTemplate(w http.ResponseWriter, name string, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := templates.ExecuteTemplate(w, name, data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// redirectBack returns the browser to the page the form was posted from
+func redirectBack(w http.ResponseWriter, r *http.Request) {
+	target := "/reader"
+	if referer, err := url.Parse(r.Referer()); err == nil && referer.Host == r.Host && referer.Path == "/reader" {
+		target = referer.RequestURI()
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+func (srv *Server) handleLoginPage(w http.ResponseWriter, r *http.Request) {
+	renderTemplate(w, "login.html", loginPage{Title: "Sign in"})
+}
+
+func (srv *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	token := r.PostFormValue("token")
+	_, err := srv.state.Db.GetUserFromAPIToken(r.Context(), auth.HashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusUnauthorized)
+		renderTemplate(w, "login.html", loginPage{Title: "Sign in", Error: "Unknown token"})
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name: sessionCookie,
+		Value: token,
+		Path: "/",
+		HttpOnly: true,
+		Secure: r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+		Expires: time.Now().Add(30 * 24 * time.Hour),
+	})
+	http.Redirect(w, r, "/reader", http.StatusSeeOther)
+}
+
+func (srv *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name: sessionCookie,
+		Value: "",
+		Path: "/",
+		MaxAge: -1,
+	})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+func (srv *Server) handleReader(w http.ResponseWriter, r *http.Request) {
+	user := requestUser(r)
+	page := readerPage{
+		Title: "Reader",
+		User: user,
+		IncludeRead: r.URL.Query().Get("all") == "1",
+		Page: 1,
+	}
+	if value, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && value > 1 {
+		page.Page = value
+	}
+	params := database.GetPostsForUserParams{
+		UserID: user.ID,
+		IncludeRead: page.IncludeRead,
+		// One extra post tells us whether there is another page
+		PostLimit: readerPageSize + 1,
+		PostOffset: int32((page.Page - 1) * readerPageSize),
+	}
+	if feedID, err := uuid.Parse(r.URL.Query().Get("feed")); err == nil {
+		page.FeedID = feedID.String()
+		params.FeedID = uuid.NullUUID{UUID: feedID, Valid: true}
+	}
+	var err error
+	page.Follows, err = srv.state.Db.GetFeedFollowsForUser(r.Context(), user.Name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	page.Posts, err = srv.state.Db.GetPostsForUser(r.Context(), params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(page.Posts) > readerPageSize {
+		page.HasMore = true
+		page.Posts = page.Posts[:readerPageSize]
+	}
+	renderTemplate(w, "reader.html", page)
+}
+
+// readerPost returns the post named in the path, answering with an error
+// unless it is in one of the user's feeds
+func (srv *Server) readerPost(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	postID, err := uuid.Parse(r.PathValue("postID"))
+	if err != nil {
+		http.Error(w, "invalid post id", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	inFeeds, err := srv.state.Db.PostInUserFeeds(r.Context(), database.PostInUserFeedsParams{
+		PostID: postID,
+		UserID: requestUser(r).ID,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return uuid.Nil, false
+	}
+	if !inFeeds {
+		http.Error(w, "post not found", http.StatusNotFound)
+		return uuid.Nil, false
+	}
+	return postID, true
+}
+
+func (srv *Server) handleReaderMarkRead(w http.ResponseWriter, r *http.Request) {
+	postID, ok := srv.readerPost(w, r)
+	if !ok {
+		return
+	}
+	err := srv.state.Db.MarkPostRead(r.Context(), database.MarkPostReadParams{
+		UserID: requestUser(r).ID,
+		PostID: postID,
+		ReadAt: time.Now().UTC(),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	redirectBack(w, r)
+}
+
+func (srv *Server) handleReaderMarkUnread(w http.ResponseWriter, r *http.Request) {
+	postID, ok := srv.readerPost(w, r)
+	if !ok {
+		return
+	}
+	err := srv.state.Db.MarkPostUnread(r.Context(), database.MarkPostUnreadParams{
+		UserID: requestUser(r).ID,
+		PostID: postID,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	redirectBack(w, r)
+}
+
+func (srv *Server) handleReaderFollow(w http.ResponseWriter, r *http.Request) {
+	feed, err := srv.state.Db.GetFeed(r.Context(), r.PostFormValue("url"))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "feed not found; add it with gator addfeed first", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	utcTime := time.Now().UTC()
+	_, err = srv.state.Db.CreateFeedFollows(r.Context(), database.CreateFeedFollowsParams{
+		ID: uuid.New(),
+		CreatedAt: utcTime,
+		UpdatedAt: utcTime,
+		UserID: requestUser(r).ID,
+		FeedID: feed.ID,
+	})
+	if database.IsUniqueViolation(err) {
+		http.Error(w, "already following feed", http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	redirectBack(w, r)
+}
+
+func (srv *Server) handleReaderUnfollow(w http.ResponseWriter, r *http.Request) {
+	feedID, err := uuid.Parse(r.PathValue("feedID"))
+	if err != nil {
+		http.Error(w, "invalid feed id", http.StatusBadRequest)
+		return
+	}
+	err = srv.state.Db.DeleteFeedFollow(r.Context(), database.DeleteFeedFollowParams{
+		UserID: requestUser(r).ID,
+		FeedID: feedID,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/reader", http.StatusSeeOther)
+}
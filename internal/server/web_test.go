@@ -0,0 +1,226 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theMagicRabbit/gator/internal/database"
+)
+
+// web sends a browser request signed in with the token, or signed out when
+// the token is empty. Redirects are returned rather than followed.
+func (ts *testServer) web(t *testing.T, token, method, path string, form url.Values) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL + path, strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if token != "" {
+		req.AddCookie(&http.Cookie{Name: sessionCookie, Value: token})
+	}
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+	return res
+}
+
+// webPage returns the body of a page that must load
+func (ts *testServer) webPage(t *testing.T, token, path string) string {
+	t.Helper()
+	res := ts.web(t, token, http.MethodGet, path, nil)
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("%s: status %d, %s", path, res.StatusCode, body)
+	}
+	return string(body)
+}
+
+// unreadCount returns how many posts the user has not read
+func (ts *testServer) unreadCount(t *testing.T, user database.User) int {
+	t.Helper()
+	posts, err := ts.state.Db.GetPostsForUser(context.Background(), database.GetPostsForUserParams{UserID: user.ID, PostLimit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(posts)
+}
+
+func TestWebLogin(t *testing.T) {
+	ts := newTestServer(t)
+	_, token := ts.addUser(t, "gator")
+
+	res := ts.web(t, "", http.MethodGet, "/reader", nil)
+	if res.StatusCode != http.StatusSeeOther || res.Header.Get("Location") != "/login" {
+		t.Errorf("signed out reader got status %d to %q, want login redirect", res.StatusCode, res.Header.Get("Location"))
+	}
+	res = ts.web(t, "wrong", http.MethodGet, "/reader", nil)
+	if res.StatusCode != http.StatusSeeOther || res.Header.Get("Location") != "/login" {
+		t.Errorf("reader with a wrong session got status %d to %q, want login redirect", res.StatusCode, res.Header.Get("Location"))
+	}
+
+	res = ts.web(t, "", http.MethodPost, "/login", url.Values{"token": {"wrong"}})
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("login with a wrong token got status %d, want %d", res.StatusCode, http.StatusUnauthorized)
+	}
+	res = ts.web(t, "", http.MethodPost, "/login", url.Values{"token": {token}})
+	if res.StatusCode != http.StatusSeeOther || res.Header.Get("Location") != "/reader" {
+		t.Errorf("login got status %d to %q, want reader redirect", res.StatusCode, res.Header.Get("Location"))
+	}
+	var session string
+	for _, cookie := range res.Cookies() {
+		if cookie.Name == sessionCookie {
+			session = cookie.Value
+		}
+	}
+	if session != token {
+		t.Fatalf("login set session %q, want the token", session)
+	}
+	if body := ts.webPage(t, session, "/reader"); !strings.Contains(body, "gator") {
+		t.Errorf("reader does not show the user's name")
+	}
+}
+
+func TestWebPagination(t *testing.T) {
+	ts := newTestServer(t)
+	user, token := ts.addUser(t, "gator")
+	feed := ts.addFeed(t, user, "https://example.com", "")
+	for i := range readerPageSize + 5 {
+		ts.addPost(t, feed, fmt.Sprintf("post%02d", i), time.Duration(i) * time.Hour)
+	}
+
+	tests := []struct {
+		path	string
+		posts	int
+		first	string
+		newer	bool
+		older	bool
+	}{
+		{path: "/reader", posts: readerPageSize, first: "post00", older: true},
+		{path: "/reader?page=2", posts: 5, first: "post25", newer: true},
+		{path: "/reader?page=3", posts: 0, newer: true},
+	}
+	for _, test := range tests {
+		body := ts.webPage(t, token, test.path)
+		if got := strings.Count(body, "<article"); got != test.posts {
+			t.Errorf("%s shows %d posts, want %d", test.path, got, test.posts)
+		}
+		if test.first != "" && !strings.Contains(body, ">" + test.first + "<") {
+			t.Errorf("%s does not start at %s", test.path, test.first)
+		}
+		if got := strings.Contains(body, "Newer"); got != test.newer {
+			t.Errorf("%s links to newer posts: %t, want %t", test.path, got, test.newer)
+		}
+		if got := strings.Contains(body, "Older"); got != test.older {
+			t.Errorf("%s links to older posts: %t, want %t", test.path, got, test.older)
+		}
+	}
+}
+
+func TestWebMarkRead(t *testing.T) {
+	ts := newTestServer(t)
+	user, token := ts.addUser(t, "gator")
+	post := ts.addPost(t, ts.addFeed(t, user, "https://example.com", ""), "mine", time.Hour)
+	other, _ := ts.addUser(t, "other")
+	otherFeed := ts.addFeed(t, other, "https://other.example.com", "")
+	hidden := ts.addPost(t, otherFeed, "theirs", time.Hour)
+
+	res := ts.web(t, token, http.MethodPost, "/reader/posts/" + post.ID.String() + "/read", nil)
+	if res.StatusCode != http.StatusSeeOther {
+		t.Errorf("mark read got status %d, want %d", res.StatusCode, http.StatusSeeOther)
+	}
+	if got := ts.unreadCount(t, user); got != 0 {
+		t.Errorf("%d unread posts after mark read, want 0", got)
+	}
+	res = ts.web(t, token, http.MethodPost, "/reader/posts/" + post.ID.String() + "/unread", nil)
+	if res.StatusCode != http.StatusSeeOther {
+		t.Errorf("mark unread got status %d, want %d", res.StatusCode, http.StatusSeeOther)
+	}
+	if got := ts.unreadCount(t, user); got != 1 {
+		t.Errorf("%d unread posts after mark unread, want 1", got)
+	}
+
+	for _, action := range []string{"read", "unread"} {
+		res = ts.web(t, token, http.MethodPost, "/reader/posts/" + hidden.ID.String() + "/" + action, nil)
+		if res.StatusCode != http.StatusNotFound {
+			t.Errorf("mark %s of another user's post got status %d, want %d", action, res.StatusCode, http.StatusNotFound)
+		}
+		res = ts.web(t, token, http.MethodPost, "/reader/posts/not-a-uuid/" + action, nil)
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("mark %s of a bad id got status %d, want %d", action, res.StatusCode, http.StatusBadRequest)
+		}
+	}
+	// Following the feed later shows its post still unread
+	_, err := ts.state.Db.CreateFeedFollows(context.Background(), database.CreateFeedFollowsParams{
+		ID: uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		UserID: user.ID,
+		FeedID: otherFeed.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ts.unreadCount(t, user); got != 2 {
+		t.Errorf("%d unread posts after following the other feed, want 2", got)
+	}
+}
+
+func TestWebFollow(t *testing.T) {
+	ts := newTestServer(t)
+	owner, _ := ts.addUser(t, "owner")
+	feed := ts.addFeed(t, owner, "https://example.com", "")
+	user, token := ts.addUser(t, "gator")
+
+	tests := []struct {
+		name	string
+		url		string
+		status	int
+	}{
+		{name: "feed", url: feed.Url, status: http.StatusSeeOther},
+		{name: "again", url: feed.Url, status: http.StatusConflict},
+		{name: "unknown feed", url: "https://unknown.example.com", status: http.StatusNotFound},
+	}
+	for _, test := range tests {
+		res := ts.web(t, token, http.MethodPost, "/reader/follow", url.Values{"url": {test.url}})
+		if res.StatusCode != test.status {
+			t.Errorf("follow %s: status %d, want %d", test.name, res.StatusCode, test.status)
+		}
+	}
+	follows, err := ts.state.Db.GetFeedFollowsForUser(context.Background(), user.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(follows) != 1 || follows[0].FeedID != feed.ID {
+		t.Errorf("follows are %+v, want %s", follows, feed.ID)
+	}
+
+	res := ts.web(t, token, http.MethodPost, "/reader/feeds/" + feed.ID.String() + "/unfollow", nil)
+	if res.StatusCode != http.StatusSeeOther {
+		t.Errorf("unfollow got status %d, want %d", res.StatusCode, http.StatusSeeOther)
+	}
+	follows, err = ts.state.Db.GetFeedFollowsForUser(context.Background(), user.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(follows) != 0 {
+		t.Errorf("follows after unfollow are %+v, want none", follows)
+	}
+}
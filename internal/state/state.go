@@ -2,11 +2,10 @@ package state
 
 import (
 	"github.com/theMagicRabbit/gator/internal/config"
-	"github.com/theMagicRabbit/gator/internal/database"
+	"github.com/theMagicRabbit/gator/internal/store"
 )
 
 type State struct {
 	Config *config.Config;
-	Db *database.Queries;
+	Db store.Store;
 }
-
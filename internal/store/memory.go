@@ -0,0 +1,1373 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theMagicRabbit/gator/internal/database"
+)
+
+// Memory is a Store that keeps everything in memory, for tests and trying
+// gator out. It follows the database queries, including their ordering,
+// cascading deletes and unique constraints.
+type Memory struct {
+	*memoryState
+	// inTx is set on the Memory handed to a transaction, which already
+	// holds the gate
+	inTx bool
+}
+
+type memoryState struct {
+	// gate is held by a transaction for as long as it runs, and by each call
+	// made outside a transaction, so that no change can land in the middle
+	// of a transaction and be lost when it rolls back
+	gate	sync.Mutex
+	mu		sync.Mutex
+	data	memoryData
+}
+
+type memoryData struct {
+	users			[]database.User
+	feeds			[]database.Feed
+	follows			[]database.FeedFollow
+	posts			[]database.Post
+	postFeeds		[]database.PostFeed
+	reads			[]database.PostRead
+	stars			[]database.PostStar
+	apiTokens		[]database.ApiToken
+	publishTokens	[]database.PublishToken
+	passwords		[]database.UserPassword
+	sessions		[]database.Session
+	feedSerial		int64
+	postSerial		int64
+}
+
+// NewMemory returns an empty in-memory store
+func NewMemory() *Memory {
+	return &Memory{memoryState: &memoryState{}}
+}
+
+// lock locks the store for one call and returns the function that unlocks
+// it. Calls made outside a transaction wait for any open transaction.
+func (m *Memory) lock() func() {
+	if !m.inTx {
+		m.gate.Lock()
+	}
+	m.mu.Lock()
+	return func() {
+		m.mu.Unlock()
+		if !m.inTx {
+			m.gate.Unlock()
+		}
+	}
+}
+
+func (d memoryData) clone() memoryData {
+	return memoryData{
+		users: slices.Clone(d.users),
+		feeds: slices.Clone(d.feeds),
+		follows: slices.Clone(d.follows),
+		posts: slices.Clone(d.posts),
+		postFeeds: slices.Clone(d.postFeeds),
+		reads: slices.Clone(d.reads),
+		stars: slices.Clone(d.stars),
+		apiTokens: slices.Clone(d.apiTokens),
+		publishTokens: slices.Clone(d.publishTokens),
+		passwords: slices.Clone(d.passwords),
+		sessions: slices.Clone(d.sessions),
+		feedSerial: d.feedSerial,
+		postSerial: d.postSerial,
+	}
+}
+
+// InTx restores the store to where it was before fn if fn fails. Other
+// callers wait until fn returns, and a nested InTx joins the transaction.
+func (m *Memory) InTx(ctx context.Context, fn func(Store) error) error {
+	if m.inTx {
+		return fn(m)
+	}
+	m.gate.Lock()
+	defer m.gate.Unlock()
+	m.mu.Lock()
+	saved := m.data.clone()
+	m.mu.Unlock()
+	err := fn(&Memory{memoryState: m.memoryState, inTx: true})
+	if err != nil {
+		m.mu.Lock()
+		m.data = saved
+		m.mu.Unlock()
+	}
+	return err
+}
+
+func uniqueViolation(constraint string) error {
+	return fmt.Errorf("%s: %w", constraint, database.ErrUniqueViolation)
+}
+
+// before compares nullable times the way SQL does: NULL compares false
+func before(a, b sql.NullTime) bool {
+	return a.Valid && b.Valid && a.Time.Before(b.Time)
+}
+
+// sortTime is the time posts are ordered by when published_at is missing
+func sortTime(p database.Post) time.Time {
+	if p.PublishedAt.Valid {
+		return p.PublishedAt.Time
+	}
+	return p.CreatedAt
+}
+
+// compareNullsLast orders nullable times newest first with NULLs at the end
+func compareNullsLast(a, b sql.NullTime) int {
+	switch {
+	case a.Valid && b.Valid:
+		return b.Time.Compare(a.Time)
+	case a.Valid:
+		return -1
+	case b.Valid:
+		return 1
+	}
+	return 0
+}
+
+func page[T any](items []T, offset, limit int32) []T {
+	if offset > 0 {
+		if int(offset) >= len(items) {
+			return nil
+		}
+		items = items[offset:]
+	}
+	if limit >= 0 && int(limit) < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+func (d *memoryData) userByID(id uuid.UUID) (database.User, bool) {
+	for _, u := range d.users {
+		if u.ID == id {
+			return u, true
+		}
+	}
+	return database.User{}, false
+}
+
+func (d *memoryData) feedIndex(id uuid.UUID) int {
+	return slices.IndexFunc(d.feeds, func(f database.Feed) bool {
+		return f.ID == id
+	})
+}
+
+func (d *memoryData) postIndex(id uuid.UUID) int {
+	return slices.IndexFunc(d.posts, func(p database.Post) bool {
+		return p.ID == id
+	})
+}
+
+func (d *memoryData) isRead(userID, postID uuid.UUID) bool {
+	return slices.ContainsFunc(d.reads, func(r database.PostRead) bool {
+		return r.UserID == userID && r.PostID == postID
+	})
+}
+
+func (d *memoryData) isStarred(userID, postID uuid.UUID) bool {
+	return slices.ContainsFunc(d.stars, func(s database.PostStar) bool {
+		return s.UserID == userID && s.PostID == postID
+	})
+}
+
+// followedFeeds returns the feeds a user follows that carry a post, and
+// the category each is followed under.
+func (d *memoryData) followedFeeds(userID, postID uuid.UUID) ([]database.Feed, []sql.NullString) {
+	var feeds []database.Feed
+	var categories []sql.NullString
+	for _, pf := range d.postFeeds {
+		if pf.PostID != postID {
+			continue
+		}
+		for _, ff := range d.follows {
+			if ff.UserID == userID && ff.FeedID == pf.FeedID {
+				if i := d.feedIndex(pf.FeedID); i >= 0 {
+					feeds = append(feeds, d.feeds[i])
+					categories = append(categories, ff.Category)
+				}
+			}
+		}
+	}
+	return feeds, categories
+}
+
+// inTimeline reports whether a post reaches a user through a followed feed,
+// optionally only through one feed or one category.
+func (d *memoryData) inTimeline(userID, postID uuid.UUID, feedID uuid.NullUUID, category sql.NullString) bool {
+	feeds, categories := d.followedFeeds(userID, postID)
+	for i, f := range feeds {
+		if feedID.Valid && f.ID != feedID.UUID {
+			continue
+		}
+		if category.Valid && (!categories[i].Valid || categories[i].String != category.String) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// feedSerialID is the lowest serial id of the followed feeds carrying a post
+func (d *memoryData) feedSerialID(userID, postID uuid.UUID) int64 {
+	feeds, _ := d.followedFeeds(userID, postID)
+	var serialID int64
+	for _, f := range feeds {
+		if serialID == 0 || f.SerialID < serialID {
+			serialID = f.SerialID
+		}
+	}
+	return serialID
+}
+
+// matchesSearch stands in for full-text search, taking the same web-style
+// syntax as the databases: words and "quoted phrases" must all occur, -
+// excludes one and OR separates alternatives. Terms match anywhere in the
+// title or description, ignoring case but without stemming. It returns how
+// many times the terms of the best matching alternative occur, or zero when
+// none matches.
+func matchesSearch(p database.Post, query string) int {
+	text := strings.ToLower(p.Title.String + " " + p.Description.String)
+	best := 0
+	for _, group := range database.ParseWebSearch(query) {
+		hits := 0
+		for _, term := range group.Include {
+			count := strings.Count(text, strings.ToLower(term))
+			if count == 0 {
+				hits = 0
+				break
+			}
+			hits += count
+		}
+		for _, term := range group.Exclude {
+			if strings.Contains(text, strings.ToLower(term)) {
+				hits = 0
+			}
+		}
+		best = max(best, hits)
+	}
+	return best
+}
+
+// searchTerms returns the terms a search looks for, leaving out the ones it
+// excludes
+func searchTerms(query string) []string {
+	var terms []string
+	for _, group := range database.ParseWebSearch(query) {
+		terms = append(terms, group.Include...)
+	}
+	return terms
+}
+
+// deleteUsers removes users and everything that cascades from them
+func (d *memoryData) deleteUsers(remove func(database.User) bool) {
+	gone := map[uuid.UUID]bool{}
+	d.users = slices.DeleteFunc(d.users, func(u database.User) bool {
+		if remove(u) {
+			gone[u.ID] = true
+			return true
+		}
+		return false
+	})
+	goneFeeds := map[uuid.UUID]bool{}
+	d.feeds = slices.DeleteFunc(d.feeds, func(f database.Feed) bool {
+		if gone[f.UserID] {
+			goneFeeds[f.ID] = true
+			return true
+		}
+		return false
+	})
+	d.follows = slices.DeleteFunc(d.follows, func(ff database.FeedFollow) bool {
+		return gone[ff.UserID] || goneFeeds[ff.FeedID]
+	})
+	d.postFeeds = slices.DeleteFunc(d.postFeeds, func(pf database.PostFeed) bool {
+		return goneFeeds[pf.FeedID]
+	})
+	d.reads = slices.DeleteFunc(d.reads, func(r database.PostRead) bool {
+		return gone[r.UserID]
+	})
+	d.stars = slices.DeleteFunc(d.stars, func(s database.PostStar) bool {
+		return gone[s.UserID]
+	})
+	d.apiTokens = slices.DeleteFunc(d.apiTokens, func(t database.ApiToken) bool {
+		return gone[t.UserID]
+	})
+	d.publishTokens = slices.DeleteFunc(d.publishTokens, func(t database.PublishToken) bool {
+		return gone[t.UserID]
+	})
+	d.passwords = slices.DeleteFunc(d.passwords, func(p database.UserPassword) bool {
+		return gone[p.UserID]
+	})
+	d.sessions = slices.DeleteFunc(d.sessions, func(s database.Session) bool {
+		return gone[s.UserID]
+	})
+}
+
+func (m *Memory) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	defer m.lock()()
+	for _, u := range m.data.users {
+		if u.ID == arg.ID {
+			return database.User{}, uniqueViolation("pk_user")
+		}
+		if u.Name == arg.Name {
+			return database.User{}, uniqueViolation("users_name_key")
+		}
+	}
+	user := database.User{
+		ID: arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name: arg.Name,
+	}
+	m.data.users = append(m.data.users, user)
+	return user, nil
+}
+
+func (m *Memory) GetUser(ctx context.Context, name string) (database.User, error) {
+	defer m.lock()()
+	for _, u := range m.data.users {
+		if u.Name == name {
+			return u, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (m *Memory) GetUserFromID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	defer m.lock()()
+	if u, ok := m.data.userByID(id); ok {
+		return u, nil
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (m *Memory) GetAllUsers(ctx context.Context) ([]string, error) {
+	defer m.lock()()
+	var names []string
+	for _, u := range m.data.users {
+		names = append(names, u.Name)
+	}
+	return names, nil
+}
+
+func (m *Memory) DeleteAllUsers(ctx context.Context) error {
+	defer m.lock()()
+	m.data.deleteUsers(func(database.User) bool {
+		return true
+	})
+	return nil
+}
+
+func (m *Memory) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	defer m.lock()()
+	m.data.deleteUsers(func(u database.User) bool {
+		return u.ID == id
+	})
+	return nil
+}
+
+func (m *Memory) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	defer m.lock()()
+	for _, f := range m.data.feeds {
+		if f.ID == arg.ID {
+			return database.Feed{}, uniqueViolation("pk_feed")
+		}
+		if f.Url == arg.Url {
+			return database.Feed{}, uniqueViolation("feeds_url_key")
+		}
+	}
+	m.data.feedSerial++
+	feed := database.Feed{
+		ID: arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name: arg.Name,
+		Url: arg.Url,
+		UserID: arg.UserID,
+		SerialID: m.data.feedSerial,
+	}
+	m.data.feeds = append(m.data.feeds, feed)
+	return feed, nil
+}
+
+func (m *Memory) GetAllFeeds(ctx context.Context) ([]database.Feed, error) {
+	defer m.lock()()
+	return slices.Clone(m.data.feeds), nil
+}
+
+func (m *Memory) GetFeed(ctx context.Context, url string) (database.Feed, error) {
+	defer m.lock()()
+	for _, f := range m.data.feeds {
+		if f.Url == url {
+			return f, nil
+		}
+	}
+	return database.Feed{}, sql.ErrNoRows
+}
+
+func (m *Memory) GetFeedFromID(ctx context.Context, id uuid.UUID) (database.Feed, error) {
+	defer m.lock()()
+	if i := m.data.feedIndex(id); i >= 0 {
+		return m.data.feeds[i], nil
+	}
+	return database.Feed{}, sql.ErrNoRows
+}
+
+// nextToFetch returns the index of the feed fetched longest ago, never
+// fetched feeds first, among the feeds that pass the filter.
+func (d *memoryData) nextToFetch(filter func(database.Feed) bool) int {
+	next := -1
+	for i, f := range d.feeds {
+		if !filter(f) {
+			continue
+		}
+		if next < 0 || (!f.LastFetchedAt.Valid && d.feeds[next].LastFetchedAt.Valid) || before(f.LastFetchedAt, d.feeds[next].LastFetchedAt) {
+			next = i
+		}
+	}
+	return next
+}
+
+func (m *Memory) ClaimNextFeedToFetch(ctx context.Context, arg database.ClaimNextFeedToFetchParams) (database.Feed, error) {
+	defer m.lock()()
+	next := m.data.nextToFetch(func(f database.Feed) bool {
+		stale := !f.LastFetchedAt.Valid || before(f.LastFetchedAt, arg.StaleBefore)
+		due := !f.NextFetchAt.Valid || (arg.ClaimedAt.Valid && !arg.ClaimedAt.Time.Before(f.NextFetchAt.Time))
+		return stale && due
+	})
+	if next < 0 {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	m.data.feeds[next].LastFetchedAt = arg.ClaimedAt
+	return m.data.feeds[next], nil
+}
+
+func (m *Memory) MarkFeedFetched(ctx context.Context, arg database.MarkFeedFetchedParams) (database.Feed, error) {
+	defer m.lock()()
+	i := m.data.feedIndex(arg.ID)
+	if i < 0 {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	f := &m.data.feeds[i]
+	f.UpdatedAt = arg.UpdatedAt
+	f.LastFetchedAt = sql.NullTime{Time: arg.UpdatedAt, Valid: true}
+	f.Etag = arg.Etag
+	f.LastModified = arg.LastModified
+	f.ConsecutiveFailures = 0
+	f.LastError = sql.NullString{}
+	f.NextFetchAt = sql.NullTime{}
+	return *f, nil
+}
+
+func (m *Memory) MarkFeedFailed(ctx context.Context, arg database.MarkFeedFailedParams) (database.Feed, error) {
+	defer m.lock()()
+	i := m.data.feedIndex(arg.ID)
+	if i < 0 {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	f := &m.data.feeds[i]
+	f.UpdatedAt = arg.UpdatedAt
+	f.ConsecutiveFailures++
+	f.LastError = arg.LastError
+	f.NextFetchAt = arg.NextFetchAt
+	return *f, nil
+}
+
+func (m *Memory) ResetFeedFetchState(ctx context.Context) error {
+	defer m.lock()()
+	for i := range m.data.feeds {
+		f := &m.data.feeds[i]
+		f.LastFetchedAt = sql.NullTime{}
+		f.Etag = sql.NullString{}
+		f.LastModified = sql.NullString{}
+		f.ConsecutiveFailures = 0
+		f.LastError = sql.NullString{}
+		f.NextFetchAt = sql.NullTime{}
+	}
+	return nil
+}
+
+func (m *Memory) TransferFollowedFeeds(ctx context.Context, userID uuid.UUID) error {
+	defer m.lock()()
+	for i := range m.data.feeds {
+		f := &m.data.feeds[i]
+		if f.UserID != userID {
+			continue
+		}
+		var next *database.FeedFollow
+		for j, ff := range m.data.follows {
+			if ff.FeedID == f.ID && ff.UserID != userID && (next == nil || ff.CreatedAt.Before(next.CreatedAt)) {
+				next = &m.data.follows[j]
+			}
+		}
+		if next != nil {
+			f.UserID = next.UserID
+		}
+	}
+	return nil
+}
+
+func (m *Memory) CreateFeedFollows(ctx context.Context, arg database.CreateFeedFollowsParams) (database.CreateFeedFollowsRow, error) {
+	defer m.lock()()
+	for _, ff := range m.data.follows {
+		if ff.ID == arg.ID {
+			return database.CreateFeedFollowsRow{}, uniqueViolation("feed_follows_id_key")
+		}
+		if ff.UserID == arg.UserID && ff.FeedID == arg.FeedID {
+			return database.CreateFeedFollowsRow{}, uniqueViolation("uq_user_id_feed_id")
+		}
+	}
+	user, ok := m.data.userByID(arg.UserID)
+	feedIndex := m.data.feedIndex(arg.FeedID)
+	if !ok || feedIndex < 0 {
+		return database.CreateFeedFollowsRow{}, errors.New("feed follow references a missing user or feed")
+	}
+	m.data.follows = append(m.data.follows, database.FeedFollow{
+		ID: arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID: arg.UserID,
+		FeedID: arg.FeedID,
+		Category: arg.Category,
+	})
+	return database.CreateFeedFollowsRow{
+		ID: arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID: arg.UserID,
+		FeedID: arg.FeedID,
+		Category: arg.Category,
+		Username: user.Name,
+		Feedname: m.data.feeds[feedIndex].Name,
+	}, nil
+}
+
+func (m *Memory) GetFeedFollow(ctx context.Context, arg database.GetFeedFollowParams) (database.FeedFollow, error) {
+	defer m.lock()()
+	for _, ff := range m.data.follows {
+		if ff.UserID == arg.UserID && ff.FeedID == arg.FeedID {
+			return ff, nil
+		}
+	}
+	return database.FeedFollow{}, sql.ErrNoRows
+}
+
+func (m *Memory) GetFeedFollowsForUser(ctx context.Context, name string) ([]database.GetFeedFollowsForUserRow, error) {
+	defer m.lock()()
+	var rows []database.GetFeedFollowsForUserRow
+	for _, ff := range m.data.follows {
+		user, ok := m.data.userByID(ff.UserID)
+		i := m.data.feedIndex(ff.FeedID)
+		if !ok || user.Name != name || i < 0 {
+			continue
+		}
+		rows = append(rows, database.GetFeedFollowsForUserRow{
+			Feedname: m.data.feeds[i].Name,
+			Username: user.Name,
+			Feedurl: m.data.feeds[i].Url,
+			Category: ff.Category,
+			FeedID: ff.FeedID,
+		})
+	}
+	slices.SortStableFunc(rows, func(a, b database.GetFeedFollowsForUserRow) int {
+		if a.Category.Valid != b.Category.Valid {
+			if a.Category.Valid {
+				return 1
+			}
+			return -1
+		}
+		if c := strings.Compare(a.Category.String, b.Category.String); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Feedname, b.Feedname)
+	})
+	return rows, nil
+}
+
+func (m *Memory) DeleteFeedFollow(ctx context.Context, arg database.DeleteFeedFollowParams) error {
+	defer m.lock()()
+	m.data.follows = slices.DeleteFunc(m.data.follows, func(ff database.FeedFollow) bool {
+		return ff.UserID == arg.UserID && ff.FeedID == arg.FeedID
+	})
+	return nil
+}
+
+func (m *Memory) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	defer m.lock()()
+	if m.data.postIndex(arg.ID) >= 0 {
+		return database.Post{}, uniqueViolation("pk_posts")
+	}
+	if !arg.Title.Valid && !arg.Description.Valid {
+		return database.Post{}, errors.New("post needs a title or a description")
+	}
+	m.data.postSerial++
+	post := database.Post{
+		ID: arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Title: arg.Title,
+		Description: arg.Description,
+		Url: arg.Url,
+		PublishedAt: arg.PublishedAt,
+		SerialID: m.data.postSerial,
+		Author: arg.Author,
+		Categories: slices.Clone(arg.Categories),
+	}
+	m.data.posts = append(m.data.posts, post)
+	return post, nil
+}
+
+func (m *Memory) GetPost(ctx context.Context, id uuid.UUID) (database.Post, error) {
+	defer m.lock()()
+	if i := m.data.postIndex(id); i >= 0 {
+		return m.data.posts[i], nil
+	}
+	return database.Post{}, sql.ErrNoRows
+}
+
+func (m *Memory) GetPostByURL(ctx context.Context, url sql.NullString) (database.Post, error) {
+	defer m.lock()()
+	for _, p := range m.data.posts {
+		if url.Valid && p.Url.Valid && p.Url.String == url.String {
+			return p, nil
+		}
+	}
+	return database.Post{}, sql.ErrNoRows
+}
+
+func (m *Memory) GetPostByURLNotInFeed(ctx context.Context, arg database.GetPostByURLNotInFeedParams) (database.Post, error) {
+	defer m.lock()()
+	for _, p := range m.data.posts {
+		if !arg.Url.Valid || !p.Url.Valid || p.Url.String != arg.Url.String {
+			continue
+		}
+		linked := slices.ContainsFunc(m.data.postFeeds, func(pf database.PostFeed) bool {
+			return pf.PostID == p.ID && pf.FeedID == arg.FeedID
+		})
+		if !linked {
+			return p, nil
+		}
+	}
+	return database.Post{}, sql.ErrNoRows
+}
+
+func (m *Memory) GetPostFromSerialID(ctx context.Context, serialID int64) (database.Post, error) {
+	defer m.lock()()
+	for _, p := range m.data.posts {
+		if p.SerialID == serialID {
+			return p, nil
+		}
+	}
+	return database.Post{}, sql.ErrNoRows
+}
+
+func (m *Memory) AdoptLegacyPostFeed(ctx context.Context, arg database.AdoptLegacyPostFeedParams) (database.PostFeed, error) {
+	defer m.lock()()
+	for i, pf := range m.data.postFeeds {
+		if pf.FeedID == arg.FeedID && pf.Guid == "legacy:"+arg.Url {
+			m.data.postFeeds[i].Guid = arg.Guid
+			return m.data.postFeeds[i], nil
+		}
+	}
+	return database.PostFeed{}, sql.ErrNoRows
+}
+
+func (m *Memory) CreatePostFeed(ctx context.Context, arg database.CreatePostFeedParams) (database.PostFeed, error) {
+	defer m.lock()()
+	for _, pf := range m.data.postFeeds {
+		if pf.PostID == arg.PostID && pf.FeedID == arg.FeedID {
+			return database.PostFeed{}, uniqueViolation("pk_post_feeds")
+		}
+		if pf.FeedID == arg.FeedID && pf.Guid == arg.Guid {
+			return database.PostFeed{}, uniqueViolation("uq_post_feeds_feed_id_guid")
+		}
+	}
+	postFeed := database.PostFeed{
+		PostID: arg.PostID,
+		FeedID: arg.FeedID,
+		Guid: arg.Guid,
+		CreatedAt: arg.CreatedAt,
+	}
+	m.data.postFeeds = append(m.data.postFeeds, postFeed)
+	return postFeed, nil
+}
+
+// deletePosts removes unstarred posts that pass the filter, along with their
+// feed links and read marks. Stars keep posts from being deleted.
+func (d *memoryData) deletePosts(remove func(database.Post) bool) {
+	gone := map[uuid.UUID]bool{}
+	d.posts = slices.DeleteFunc(d.posts, func(p database.Post) bool {
+		if !remove(p) || slices.ContainsFunc(d.stars, func(s database.PostStar) bool {
+			return s.PostID == p.ID
+		}) {
+			return false
+		}
+		gone[p.ID] = true
+		return true
+	})
+	d.postFeeds = slices.DeleteFunc(d.postFeeds, func(pf database.PostFeed) bool {
+		return gone[pf.PostID]
+	})
+	d.reads = slices.DeleteFunc(d.reads, func(r database.PostRead) bool {
+		return gone[r.PostID]
+	})
+}
+
+func (m *Memory) DeleteOrphanedPosts(ctx context.Context) error {
+	defer m.lock()()
+	m.data.deletePosts(func(p database.Post) bool {
+		return !slices.ContainsFunc(m.data.postFeeds, func(pf database.PostFeed) bool {
+			return pf.PostID == p.ID
+		})
+	})
+	return nil
+}
+
+func (m *Memory) DeleteUnstarredPosts(ctx context.Context) error {
+	defer m.lock()()
+	m.data.deletePosts(func(database.Post) bool {
+		return true
+	})
+	return nil
+}
+
+// LockPostURL has nothing to do, as transactions already run one at a time
+func (m *Memory) LockPostURL(ctx context.Context, url string) error {
+	return nil
+}
+
+func (m *Memory) PostFeedExists(ctx context.Context, arg database.PostFeedExistsParams) (bool, error) {
+	defer m.lock()()
+	return slices.ContainsFunc(m.data.postFeeds, func(pf database.PostFeed) bool {
+		return pf.FeedID == arg.FeedID && pf.Guid == arg.Guid
+	}), nil
+}
+
+func (m *Memory) PostInUserFeeds(ctx context.Context, arg database.PostInUserFeedsParams) (bool, error) {
+	defer m.lock()()
+	return m.data.inTimeline(arg.UserID, arg.PostID, uuid.NullUUID{}, sql.NullString{}), nil
+}
+
+func (m *Memory) GetPostsForUser(ctx context.Context, arg database.GetPostsForUserParams) ([]database.GetPostsForUserRow, error) {
+	defer m.lock()()
+	var rows []database.GetPostsForUserRow
+	for _, p := range m.data.posts {
+		if !m.data.inTimeline(arg.UserID, p.ID, arg.FeedID, arg.Category) {
+			continue
+		}
+		isRead := m.data.isRead(arg.UserID, p.ID)
+		if isRead && !arg.IncludeRead {
+			continue
+		}
+		if arg.Search.Valid && matchesSearch(p, arg.Search.String) == 0 {
+			continue
+		}
+		rows = append(rows, database.GetPostsForUserRow{
+			ID: p.ID,
+			CreatedAt: p.CreatedAt,
+			UpdatedAt: p.UpdatedAt,
+			Title: p.Title,
+			Description: p.Description,
+			Url: p.Url,
+			PublishedAt: p.PublishedAt,
+			SerialID: p.SerialID,
+			Author: p.Author,
+			Categories: p.Categories,
+			IsRead: isRead,
+		})
+	}
+	slices.SortStableFunc(rows, func(a, b database.GetPostsForUserRow) int {
+		return compareNullsLast(a.PublishedAt, b.PublishedAt)
+	})
+	return page(rows, arg.PostOffset, arg.PostLimit), nil
+}
+
+// snippet marks the search terms in a post's description, or its title when
+// it has none, the way the database search headlines do.
+func snippet(p database.Post, query string) string {
+	text := p.Description.String
+	if !p.Description.Valid {
+		text = p.Title.String
+	}
+	for _, word := range searchTerms(query) {
+		lower := strings.ToLower(text)
+		var marked strings.Builder
+		start := 0
+		for {
+			i := strings.Index(lower[start:], strings.ToLower(word))
+			if i < 0 {
+				break
+			}
+			i += start
+			marked.WriteString(text[start:i])
+			marked.WriteString(database.HeadlineStart + text[i:i+len(word)] + database.HeadlineStop)
+			start = i + len(word)
+		}
+		marked.WriteString(text[start:])
+		text = marked.String()
+	}
+	return text
+}
+
+func (m *Memory) SearchPostsForUser(ctx context.Context, arg database.SearchPostsForUserParams) ([]database.SearchPostsForUserRow, error) {
+	defer m.lock()()
+	var rows []database.SearchPostsForUserRow
+	for _, p := range m.data.posts {
+		hits := matchesSearch(p, arg.Query)
+		if hits == 0 || !m.data.inTimeline(arg.UserID, p.ID, arg.FeedID, sql.NullString{}) {
+			continue
+		}
+		if arg.Since.Valid && (!p.PublishedAt.Valid || p.PublishedAt.Time.Before(arg.Since.Time)) {
+			continue
+		}
+		if arg.Until.Valid && (!p.PublishedAt.Valid || !p.PublishedAt.Time.Before(arg.Until.Time)) {
+			continue
+		}
+		rows = append(rows, database.SearchPostsForUserRow{
+			ID: p.ID,
+			Title: p.Title,
+			Url: p.Url,
+			PublishedAt: p.PublishedAt,
+			Rank: float32(hits),
+			Snippet: snippet(p, arg.Query),
+		})
+	}
+	slices.SortStableFunc(rows, func(a, b database.SearchPostsForUserRow) int {
+		if a.Rank != b.Rank {
+			if a.Rank > b.Rank {
+				return -1
+			}
+			return 1
+		}
+		return compareNullsLast(a.PublishedAt, b.PublishedAt)
+	})
+	return page(rows, 0, arg.PostLimit), nil
+}
+
+func (m *Memory) CountPostsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	defer m.lock()()
+	var count int64
+	for _, p := range m.data.posts {
+		if m.data.inTimeline(userID, p.ID, uuid.NullUUID{}, sql.NullString{}) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *Memory) GetUnreadPostSerialIDsForUser(ctx context.Context, userID uuid.UUID) ([]int64, error) {
+	defer m.lock()()
+	var ids []int64
+	for _, p := range m.data.posts {
+		if m.data.inTimeline(userID, p.ID, uuid.NullUUID{}, sql.NullString{}) && !m.data.isRead(userID, p.ID) {
+			ids = append(ids, p.SerialID)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (m *Memory) GetStarredPostSerialIDsForUser(ctx context.Context, userID uuid.UUID) ([]int64, error) {
+	defer m.lock()()
+	var ids []int64
+	for _, p := range m.data.posts {
+		if m.data.isStarred(userID, p.ID) {
+			ids = append(ids, p.SerialID)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (m *Memory) GetFeverFeedsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeverFeedsForUserRow, error) {
+	defer m.lock()()
+	var rows []database.GetFeverFeedsForUserRow
+	for _, ff := range m.data.follows {
+		i := m.data.feedIndex(ff.FeedID)
+		if ff.UserID != userID || i < 0 {
+			continue
+		}
+		f := m.data.feeds[i]
+		rows = append(rows, database.GetFeverFeedsForUserRow{
+			ID: f.ID,
+			SerialID: f.SerialID,
+			Name: f.Name,
+			Url: f.Url,
+			LastFetchedAt: f.LastFetchedAt,
+			Category: ff.Category,
+		})
+	}
+	slices.SortFunc(rows, func(a, b database.GetFeverFeedsForUserRow) int {
+		return int(a.SerialID - b.SerialID)
+	})
+	return rows, nil
+}
+
+func (m *Memory) GetFeverItemsForUser(ctx context.Context, arg database.GetFeverItemsForUserParams) ([]database.GetFeverItemsForUserRow, error) {
+	defer m.lock()()
+	var rows []database.GetFeverItemsForUserRow
+	for _, p := range m.data.posts {
+		if !m.data.inTimeline(arg.UserID, p.ID, uuid.NullUUID{}, sql.NullString{}) {
+			continue
+		}
+		if arg.SinceID.Valid && p.SerialID <= arg.SinceID.Int64 {
+			continue
+		}
+		if arg.MaxID.Valid && p.SerialID >= arg.MaxID.Int64 {
+			continue
+		}
+		if arg.WithIds != nil && !slices.Contains(arg.WithIds, p.SerialID) {
+			continue
+		}
+		rows = append(rows, database.GetFeverItemsForUserRow{
+			ID: p.ID,
+			SerialID: p.SerialID,
+			Title: p.Title,
+			Description: p.Description,
+			Url: p.Url,
+			PublishedAt: p.PublishedAt,
+			CreatedAt: p.CreatedAt,
+			Author: p.Author,
+			FeedSerialID: m.data.feedSerialID(arg.UserID, p.ID),
+			IsRead: m.data.isRead(arg.UserID, p.ID),
+			IsSaved: m.data.isStarred(arg.UserID, p.ID),
+		})
+	}
+	slices.SortFunc(rows, func(a, b database.GetFeverItemsForUserRow) int {
+		if arg.NewestFirst {
+			return int(b.SerialID - a.SerialID)
+		}
+		return int(a.SerialID - b.SerialID)
+	})
+	return page(rows, 0, arg.ItemLimit), nil
+}
+
+func (m *Memory) GetReaderItemsForUser(ctx context.Context, arg database.GetReaderItemsForUserParams) ([]database.GetReaderItemsForUserRow, error) {
+	defer m.lock()()
+	var rows []database.GetReaderItemsForUserRow
+	for _, p := range m.data.posts {
+		if !m.data.inTimeline(arg.UserID, p.ID, arg.FeedID, arg.Category) {
+			continue
+		}
+		isRead := m.data.isRead(arg.UserID, p.ID)
+		isStarred := m.data.isStarred(arg.UserID, p.ID)
+		if (arg.StarredOnly && !isStarred) || (arg.ReadOnly && !isRead) || (arg.ExcludeRead && isRead) {
+			continue
+		}
+		t := sortTime(p)
+		if (arg.NewerThan.Valid && t.Before(arg.NewerThan.Time)) || (arg.OlderThan.Valid && !t.Before(arg.OlderThan.Time)) {
+			continue
+		}
+		if arg.WithIds != nil && !slices.Contains(arg.WithIds, p.SerialID) {
+			continue
+		}
+		rows = append(rows, database.GetReaderItemsForUserRow{
+			ID: p.ID,
+			SerialID: p.SerialID,
+			Title: p.Title,
+			Description: p.Description,
+			Url: p.Url,
+			PublishedAt: p.PublishedAt,
+			CreatedAt: p.CreatedAt,
+			Author: p.Author,
+			Categories: p.Categories,
+			FeedSerialID: m.data.feedSerialID(arg.UserID, p.ID),
+			IsRead: isRead,
+			IsStarred: isStarred,
+		})
+	}
+	itemTime := func(row database.GetReaderItemsForUserRow) time.Time {
+		if row.PublishedAt.Valid {
+			return row.PublishedAt.Time
+		}
+		return row.CreatedAt
+	}
+	slices.SortFunc(rows, func(a, b database.GetReaderItemsForUserRow) int {
+		c := itemTime(b).Compare(itemTime(a))
+		if arg.OldestFirst {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+		return int(b.SerialID - a.SerialID)
+	})
+	return page(rows, arg.ItemOffset, arg.ItemLimit), nil
+}
+
+// markRead marks posts read for a user, leaving posts that are already read
+func (d *memoryData) markRead(userID uuid.UUID, readAt time.Time, postIDs []uuid.UUID) {
+	for _, postID := range postIDs {
+		if !d.isRead(userID, postID) {
+			d.reads = append(d.reads, database.PostRead{UserID: userID, PostID: postID, ReadAt: readAt})
+		}
+	}
+}
+
+func (m *Memory) MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error {
+	defer m.lock()()
+	if m.data.postIndex(arg.PostID) < 0 {
+		return errors.New("post read references a missing post")
+	}
+	m.data.markRead(arg.UserID, arg.ReadAt, []uuid.UUID{arg.PostID})
+	return nil
+}
+
+func (m *Memory) MarkPostUnread(ctx context.Context, arg database.MarkPostUnreadParams) error {
+	defer m.lock()()
+	m.data.reads = slices.DeleteFunc(m.data.reads, func(r database.PostRead) bool {
+		return r.UserID == arg.UserID && r.PostID == arg.PostID
+	})
+	return nil
+}
+
+func (m *Memory) MarkFeedRead(ctx context.Context, arg database.MarkFeedReadParams) error {
+	defer m.lock()()
+	var postIDs []uuid.UUID
+	for _, pf := range m.data.postFeeds {
+		if pf.FeedID == arg.FeedID {
+			postIDs = append(postIDs, pf.PostID)
+		}
+	}
+	m.data.markRead(arg.UserID, arg.ReadAt, postIDs)
+	return nil
+}
+
+func (m *Memory) MarkFeedReadBefore(ctx context.Context, arg database.MarkFeedReadBeforeParams) error {
+	defer m.lock()()
+	var postIDs []uuid.UUID
+	for _, pf := range m.data.postFeeds {
+		i := m.data.postIndex(pf.PostID)
+		if pf.FeedID == arg.FeedID && i >= 0 && !m.data.posts[i].CreatedAt.After(arg.CreatedBefore) {
+			postIDs = append(postIDs, pf.PostID)
+		}
+	}
+	m.data.markRead(arg.UserID, arg.ReadAt, postIDs)
+	return nil
+}
+
+func (m *Memory) MarkFeedUnread(ctx context.Context, arg database.MarkFeedUnreadParams) error {
+	defer m.lock()()
+	m.data.reads = slices.DeleteFunc(m.data.reads, func(r database.PostRead) bool {
+		return r.UserID == arg.UserID && slices.ContainsFunc(m.data.postFeeds, func(pf database.PostFeed) bool {
+			return pf.FeedID == arg.FeedID && pf.PostID == r.PostID
+		})
+	})
+	return nil
+}
+
+func (m *Memory) StarPost(ctx context.Context, arg database.StarPostParams) error {
+	defer m.lock()()
+	if m.data.postIndex(arg.PostID) < 0 {
+		return errors.New("post star references a missing post")
+	}
+	if !m.data.isStarred(arg.UserID, arg.PostID) {
+		m.data.stars = append(m.data.stars, database.PostStar{UserID: arg.UserID, PostID: arg.PostID, StarredAt: arg.StarredAt})
+	}
+	return nil
+}
+
+func (m *Memory) UnstarPost(ctx context.Context, arg database.UnstarPostParams) error {
+	defer m.lock()()
+	m.data.stars = slices.DeleteFunc(m.data.stars, func(s database.PostStar) bool {
+		return s.UserID == arg.UserID && s.PostID == arg.PostID
+	})
+	return nil
+}
+
+func (m *Memory) GetStarredPostsForUser(ctx context.Context, arg database.GetStarredPostsForUserParams) ([]database.GetStarredPostsForUserRow, error) {
+	defer m.lock()()
+	var rows []database.GetStarredPostsForUserRow
+	for _, s := range m.data.stars {
+		i := m.data.postIndex(s.PostID)
+		if s.UserID != arg.UserID || i < 0 {
+			continue
+		}
+		p := m.data.posts[i]
+		rows = append(rows, database.GetStarredPostsForUserRow{
+			ID: p.ID,
+			CreatedAt: p.CreatedAt,
+			UpdatedAt: p.UpdatedAt,
+			Title: p.Title,
+			Description: p.Description,
+			Url: p.Url,
+			PublishedAt: p.PublishedAt,
+			SerialID: p.SerialID,
+			Author: p.Author,
+			Categories: p.Categories,
+			StarredAt: s.StarredAt,
+		})
+	}
+	slices.SortStableFunc(rows, func(a, b database.GetStarredPostsForUserRow) int {
+		return b.StarredAt.Compare(a.StarredAt)
+	})
+	return page(rows, 0, arg.Limit), nil
+}
+
+func (m *Memory) CreateAPIToken(ctx context.Context, arg database.CreateAPITokenParams) (database.ApiToken, error) {
+	defer m.lock()()
+	for _, t := range m.data.apiTokens {
+		if t.ID == arg.ID {
+			return database.ApiToken{}, uniqueViolation("pk_api_tokens")
+		}
+		if t.TokenHash == arg.TokenHash {
+			return database.ApiToken{}, uniqueViolation("api_tokens_token_hash_key")
+		}
+		if arg.FeverKey.Valid && t.FeverKey == arg.FeverKey {
+			return database.ApiToken{}, uniqueViolation("api_tokens_fever_key_key")
+		}
+	}
+	token := database.ApiToken{
+		ID: arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UserID: arg.UserID,
+		Name: arg.Name,
+		TokenHash: arg.TokenHash,
+		FeverKey: arg.FeverKey,
+	}
+	m.data.apiTokens = append(m.data.apiTokens, token)
+	return token, nil
+}
+
+func (m *Memory) DeleteAPITokensForUser(ctx context.Context, userID uuid.UUID) error {
+	defer m.lock()()
+	m.data.apiTokens = slices.DeleteFunc(m.data.apiTokens, func(t database.ApiToken) bool {
+		return t.UserID == userID
+	})
+	return nil
+}
+
+// tokenUser returns the user of the first token that matches
+func (d *memoryData) tokenUser(match func(database.ApiToken) bool) (database.User, error) {
+	for _, t := range d.apiTokens {
+		if match(t) {
+			if u, ok := d.userByID(t.UserID); ok {
+				return u, nil
+			}
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (m *Memory) GetUserFromAPIToken(ctx context.Context, tokenHash string) (database.User, error) {
+	defer m.lock()()
+	return m.data.tokenUser(func(t database.ApiToken) bool {
+		return t.TokenHash == tokenHash
+	})
+}
+
+func (m *Memory) GetUserFromFeverKey(ctx context.Context, feverKey sql.NullString) (database.User, error) {
+	defer m.lock()()
+	return m.data.tokenUser(func(t database.ApiToken) bool {
+		return feverKey.Valid && t.FeverKey == feverKey
+	})
+}
+
+func (m *Memory) SetPublishToken(ctx context.Context, arg database.SetPublishTokenParams) error {
+	defer m.lock()()
+	for _, t := range m.data.publishTokens {
+		if t.TokenHash == arg.TokenHash && t.UserID != arg.UserID {
+			return uniqueViolation("publish_tokens_token_hash_key")
+		}
+	}
+	m.data.publishTokens = slices.DeleteFunc(m.data.publishTokens, func(t database.PublishToken) bool {
+		return t.UserID == arg.UserID
+	})
+	m.data.publishTokens = append(m.data.publishTokens, database.PublishToken{
+		UserID: arg.UserID,
+		CreatedAt: arg.CreatedAt,
+		TokenHash: arg.TokenHash,
+	})
+	return nil
+}
+
+func (m *Memory) GetUserFromPublishToken(ctx context.Context, tokenHash string) (database.User, error) {
+	defer m.lock()()
+	for _, t := range m.data.publishTokens {
+		if t.TokenHash == tokenHash {
+			if u, ok := m.data.userByID(t.UserID); ok {
+				return u, nil
+			}
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (m *Memory) SetUserPassword(ctx context.Context, arg database.SetUserPasswordParams) error {
+	defer m.lock()()
+	if _, ok := m.data.userByID(arg.UserID); !ok {
+		return errors.New("user password references a missing user")
+	}
+	m.data.passwords = slices.DeleteFunc(m.data.passwords, func(p database.UserPassword) bool {
+		return p.UserID == arg.UserID
+	})
+	m.data.passwords = append(m.data.passwords, database.UserPassword{
+		UserID: arg.UserID,
+		UpdatedAt: arg.UpdatedAt,
+		PasswordHash: arg.PasswordHash,
+	})
+	return nil
+}
+
+func (m *Memory) GetUserPasswordHash(ctx context.Context, userID uuid.UUID) (string, error) {
+	defer m.lock()()
+	for _, p := range m.data.passwords {
+		if p.UserID == userID {
+			return p.PasswordHash, nil
+		}
+	}
+	return "", sql.ErrNoRows
+}
+
+func (m *Memory) DeleteUserPassword(ctx context.Context, userID uuid.UUID) error {
+	defer m.lock()()
+	m.data.passwords = slices.DeleteFunc(m.data.passwords, func(p database.UserPassword) bool {
+		return p.UserID == userID
+	})
+	return nil
+}
+
+func (m *Memory) CreateSession(ctx context.Context, arg database.CreateSessionParams) (database.Session, error) {
+	defer m.lock()()
+	for _, s := range m.data.sessions {
+		if s.ID == arg.ID {
+			return database.Session{}, uniqueViolation("pk_sessions")
+		}
+		if s.TokenHash == arg.TokenHash {
+			return database.Session{}, uniqueViolation("sessions_token_hash_key")
+		}
+	}
+	if _, ok := m.data.userByID(arg.UserID); !ok {
+		return database.Session{}, errors.New("session references a missing user")
+	}
+	session := database.Session{
+		ID: arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UserID: arg.UserID,
+		TokenHash: arg.TokenHash,
+	}
+	m.data.sessions = append(m.data.sessions, session)
+	return session, nil
+}
+
+func (m *Memory) GetUserFromSession(ctx context.Context, tokenHash string) (database.User, error) {
+	defer m.lock()()
+	for _, s := range m.data.sessions {
+		if s.TokenHash == tokenHash {
+			if u, ok := m.data.userByID(s.UserID); ok {
+				return u, nil
+			}
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (m *Memory) DeleteSession(ctx context.Context, tokenHash string) error {
+	defer m.lock()()
+	m.data.sessions = slices.DeleteFunc(m.data.sessions, func(s database.Session) bool {
+		return s.TokenHash == tokenHash
+	})
+	return nil
+}
+
+func (m *Memory) DeleteSessionsForUser(ctx context.Context, userID uuid.UUID) error {
+	defer m.lock()()
+	m.data.sessions = slices.DeleteFunc(m.data.sessions, func(s database.Session) bool {
+		return s.UserID == userID
+	})
+	return nil
+}
+
+func (m *Memory) BackupUsers(ctx context.Context) ([]database.User, error) {
+	defer m.lock()()
+	return backupRows(m.data.users, func(a, b database.User) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	}), nil
+}
+
+func (m *Memory) BackupUserPasswords(ctx context.Context) ([]database.UserPassword, error) {
+	defer m.lock()()
+	return backupRows(m.data.passwords, func(a, b database.UserPassword) int {
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	}), nil
+}
+
+func (m *Memory) BackupFeeds(ctx context.Context) ([]database.Feed, error) {
+	defer m.lock()()
+	return backupRows(m.data.feeds, func(a, b database.Feed) int {
+		return int(a.SerialID - b.SerialID)
+	}), nil
+}
+
+func (m *Memory) BackupFeedFollows(ctx context.Context) ([]database.FeedFollow, error) {
+	defer m.lock()()
+	return backupRows(m.data.follows, func(a, b database.FeedFollow) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	}), nil
+}
+
+func (m *Memory) BackupPosts(ctx context.Context) ([]database.Post, error) {
+	defer m.lock()()
+	return backupRows(m.data.posts, func(a, b database.Post) int {
+		return int(a.SerialID - b.SerialID)
+	}), nil
+}
+
+func (m *Memory) BackupPostFeeds(ctx context.Context) ([]database.PostFeed, error) {
+	defer m.lock()()
+	return backupRows(m.data.postFeeds, func(a, b database.PostFeed) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	}), nil
+}
+
+func (m *Memory) BackupPostReads(ctx context.Context) ([]database.PostRead, error) {
+	defer m.lock()()
+	return backupRows(m.data.reads, func(a, b database.PostRead) int {
+		return a.ReadAt.Compare(b.ReadAt)
+	}), nil
+}
+
+func (m *Memory) BackupPostStars(ctx context.Context) ([]database.PostStar, error) {
+	defer m.lock()()
+	return backupRows(m.data.stars, func(a, b database.PostStar) int {
+		return a.StarredAt.Compare(b.StarredAt)
+	}), nil
+}
+
+func (m *Memory) BackupAPITokens(ctx context.Context) ([]database.ApiToken, error) {
+	defer m.lock()()
+	return backupRows(m.data.apiTokens, func(a, b database.ApiToken) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	}), nil
+}
+
+func (m *Memory) BackupPublishTokens(ctx context.Context) ([]database.PublishToken, error) {
+	defer m.lock()()
+	return backupRows(m.data.publishTokens, func(a, b database.PublishToken) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	}), nil
+}
+
+// backupRows returns a sorted copy of a table, or nil when it is empty
+func backupRows[T any](rows []T, cmp func(a, b T) int) []T {
+	if len(rows) == 0 {
+		return nil
+	}
+	rows = slices.Clone(rows)
+	slices.SortStableFunc(rows, cmp)
+	return rows
+}
+
+var _ Store = (*Memory)(nil)
@@ -0,0 +1,172 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/theMagicRabbit/gator/internal/database"
+)
+
+func createUser(ctx context.Context, db Store, name string) error {
+	now := time.Now().UTC()
+	_, err := db.CreateUser(ctx, database.CreateUserParams{
+		ID: uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Name: name,
+	})
+	return err
+}
+
+func userNames(t *testing.T, db Store) []string {
+	t.Helper()
+	names, err := db.GetAllUsers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestMemoryInTxRollsBack(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemory()
+	if err := createUser(ctx, db, "kept"); err != nil {
+		t.Fatal(err)
+	}
+	failure := errors.New("failed")
+	err := db.InTx(ctx, func(tx Store) error {
+		if err := createUser(ctx, tx, "outer"); err != nil {
+			return err
+		}
+		// A nested transaction joins the outer one
+		err := tx.InTx(ctx, func(tx Store) error {
+			return createUser(ctx, tx, "nested")
+		})
+		if err != nil {
+			return err
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("InTx returned %v, want %v", err, failure)
+	}
+	if names := userNames(t, db); len(names) != 1 || names[0] != "kept" {
+		t.Errorf("users after rollback are %v, want [kept]", names)
+	}
+}
+
+// TestMemoryInTxKeepsOtherWrites checks that a write made outside an open
+// transaction waits for it instead of being undone by its rollback.
+func TestMemoryInTxKeepsOtherWrites(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemory()
+	written := make(chan error, 1)
+	err := db.InTx(ctx, func(tx Store) error {
+		if err := createUser(ctx, tx, "rolled back"); err != nil {
+			return err
+		}
+		go func() {
+			written <- createUser(ctx, db, "outside")
+		}()
+		select {
+		case err := <-written:
+			written <- err
+			t.Error("write outside the transaction finished while it was open")
+		case <-time.After(50 * time.Millisecond):
+		}
+		return errors.New("failed")
+	})
+	if err == nil {
+		t.Fatal("InTx succeeded, want the error fn returned")
+	}
+	if err := <-written; err != nil {
+		t.Fatal(err)
+	}
+	if names := userNames(t, db); len(names) != 1 || names[0] != "outside" {
+		t.Errorf("users are %v, want [outside]", names)
+	}
+}
+
+// TestMemorySearch checks that the memory store reads web-style searches as
+// the databases do
+func TestMemorySearch(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemory()
+	now := time.Now().UTC()
+	user, err := db.CreateUser(ctx, database.CreateUserParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: "gator"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := db.CreateFeed(ctx, database.CreateFeedParams{
+		ID: uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Name: "Feed",
+		Url: "https://example.com/feed",
+		UserID: user.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.CreateFeedFollows(ctx, database.CreateFeedFollowsParams{
+		ID: uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID: user.ID,
+		FeedID: feed.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, title := range []string{"Learning golang and c++", "Golang versus rust", "Rust in production"} {
+		post, err := db.CreatePost(ctx, database.CreatePostParams{
+			ID: uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			Title: sql.NullString{String: title, Valid: true},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = db.CreatePostFeed(ctx, database.CreatePostFeedParams{PostID: post.ID, FeedID: feed.ID, Guid: title, CreatedAt: now})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		search	string
+		want	[]string
+		snippet	string
+	}{
+		{search: "c++", want: []string{"Learning golang and c++"}},
+		{search: "golang -rust", want: []string{"Learning golang and c++"}},
+		{search: "production or c++", want: []string{"Learning golang and c++", "Rust in production"}},
+		{search: `"versus rust"`, want: []string{"Golang versus rust"}, snippet: "Golang \x02versus rust\x03"},
+		{search: `"rust versus"`, want: nil},
+		{search: "-golang", want: nil},
+		{search: "rust -golang", want: []string{"Rust in production"}, snippet: "\x02Rust\x03 in production"},
+	}
+	for _, test := range tests {
+		rows, err := db.SearchPostsForUser(ctx, database.SearchPostsForUserParams{Query: test.search, UserID: user.ID, PostLimit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var found []string
+		for _, row := range rows {
+			found = append(found, row.Title.String)
+		}
+		slices.Sort(found)
+		if !slices.Equal(found, test.want) {
+			t.Errorf("search %q found %q, want %q", test.search, found, test.want)
+		}
+		if test.snippet != "" && len(rows) == 1 && rows[0].Snippet != test.snippet {
+			t.Errorf("search %q snippet is %q, want %q", test.search, rows[0].Snippet, test.snippet)
+		}
+	}
+}
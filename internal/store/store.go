@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/theMagicRabbit/gator/internal/database"
+)
+
+// Store is the data gator reads and writes. Commands, the scraper and the
+// server only see this interface, so they work the same against a database
+// or against memory.
+type Store interface {
+	database.Querier
+	// InTx runs fn against a store whose changes are kept together when fn
+	// returns nil and discarded when it returns an error. Calling InTx inside
+	// fn joins the transaction that is already open.
+	InTx(ctx context.Context, fn func(Store) error) error
+}
+
+// sqlStore is a Store over a Postgres or SQLite database
+type sqlStore struct {
+	*database.Queries
+	db *sql.DB
+	tx *sql.Tx
+}
+
+// NewSQL returns a Store over a database opened for the generated queries
+func NewSQL(db *sql.DB) Store {
+	return &sqlStore{
+		Queries: database.New(db),
+		db: db,
+	}
+}
+
+func (s *sqlStore) InTx(ctx context.Context, fn func(Store) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	err = fn(&sqlStore{
+		Queries: s.Queries.WithTx(tx),
+		db: s.db,
+		tx: tx,
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
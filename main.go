@@ -1,24 +1,27 @@
 package main
 
 import (
-	"context"
 	"database/sql"
 	"embed"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/pressly/goose/v3"
 	"github.com/theMagicRabbit/gator/internal/cli"
 	"github.com/theMagicRabbit/gator/internal/config"
 	"github.com/theMagicRabbit/gator/internal/database"
 	"github.com/theMagicRabbit/gator/internal/state"
+	"github.com/theMagicRabbit/gator/internal/store"
 
 	_ "github.com/lib/pq"
 )
 
 func middlewareLoggedIn(handler func(s *state.State, cmd cli.Command, user database.User) error) func(*state.State, cli.Command) error {
 	 return func(s *state.State, cmd cli.Command) error {
-		user, err := s.Db.GetUser(context.Background(), s.Config.Current_user_name)
+		user, err := cli.CurrentUser(s)
 		if err != nil {
 			return err
 		}
@@ -30,36 +33,62 @@ func middlewareLoggedIn(handler func(s *state.State, cmd cli.Command, user datab
 	}
 }
 
-//go:embed sql/schema/*.sql
+//go:embed sql/schema/*.sql sql/sqlite/schema/*.sql sql/sqlite/queries/*.sql
 var embededMigrations embed.FS
 
-
-func main() {
-	goose.SetBaseFS(embededMigrations)
-	conf, err := config.Read()
+// openDatabase opens the database named by db_url and brings its schema up
+// to date. sqlite:// URLs name a SQLite file; anything else is Postgres.
+func openDatabase(dbURL string) (*sql.DB, error) {
+	path, isSQLite := strings.CutPrefix(dbURL, "sqlite://")
+	if !isSQLite {
+		db, err := sql.Open("postgres", dbURL)
+		if err != nil {
+			return nil, err
+		}
+		err = goose.SetDialect("postgres")
+		if err != nil {
+			return nil, err
+		}
+		return db, goose.Up(db, "sql/schema")
+	}
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(home, rest)
+	}
+	queries, err := fs.Sub(embededMigrations, "sql/sqlite/queries")
 	if err != nil {
-		os.Exit(1)
+		return nil, err
 	}
-	db, err := sql.Open("postgres", conf.Db_url)
+	db, err := database.OpenSQLite(path, queries)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return nil, err
 	}
-	err = goose.SetDialect("postgres")
+	err = goose.SetDialect("sqlite3")
+	if err != nil {
+		return nil, err
+	}
+	return db, goose.Up(db, "sql/sqlite/schema")
+}
+
+
+func main() {
+	goose.SetBaseFS(embededMigrations)
+	conf, err := config.Read()
 	if err != nil {
-		fmt.Println(err)
 		os.Exit(1)
 	}
-	err = goose.Up(db, "sql/schema")
+	db, err := openDatabase(conf.Db_url)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 
-	dbQueries := database.New(db)
 	runState := state.State{
 		Config: &conf,
-		Db: dbQueries,
+		Db: store.NewSQL(db),
 	}
 	commands := cli.Commands{
 		Commands: map[string]func(*state.State, cli.Command) error {},
@@ -67,13 +96,25 @@ func main() {
 	commands.Register("addfeed", middlewareLoggedIn(cli.HandlerAddFeed))
 	commands.Register("agg", cli.HandlerAgg)
 	commands.Register("browse", middlewareLoggedIn(cli.HandlerBrowse))
+	commands.Register("export", middlewareLoggedIn(cli.HandlerExport))
 	commands.Register("feeds", cli.HandlerFeeds)
 	commands.Register("follow", middlewareLoggedIn(cli.HandlerFollow))
 	commands.Register("following", middlewareLoggedIn(cli.HandlerFollowing))
+	commands.Register("import", middlewareLoggedIn(cli.HandlerImport))
 	commands.Register("login", cli.HandlerLogin)
+	commands.Register("passwd", middlewareLoggedIn(cli.HandlerPasswd))
+	commands.Register("publish", middlewareLoggedIn(cli.HandlerPublish))
+	commands.Register("read", middlewareLoggedIn(cli.HandlerRead))
 	commands.Register("register", cli.HandlerRegister)
 	commands.Register("reset", cli.HandlerReset)
+	commands.Register("saved", middlewareLoggedIn(cli.HandlerSaved))
+	commands.Register("search", middlewareLoggedIn(cli.HandlerSearch))
+	commands.Register("serve", cli.HandlerServe)
+	commands.Register("star", middlewareLoggedIn(cli.HandlerStar))
+	commands.Register("token", middlewareLoggedIn(cli.HandlerToken))
 	commands.Register("unfollow", middlewareLoggedIn(cli.HandlerUnfollow))
+	commands.Register("unread", middlewareLoggedIn(cli.HandlerUnread))
+	commands.Register("unstar", middlewareLoggedIn(cli.HandlerUnstar))
 	commands.Register("users", cli.HandlerUsers)
 
 	if len(os.Args) < 2 {
@@ -0,0 +1,12 @@
+-- name: CreateAPIToken :one
+INSERT INTO api_tokens (id, created_at, user_id, name, token_hash, fever_key)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetUserFromAPIToken :one
+SELECT users.* FROM users
+JOIN api_tokens ON api_tokens.user_id = users.id
+WHERE api_tokens.token_hash = $1;
+
+-- name: DeleteAPITokensForUser :exec
+DELETE FROM api_tokens WHERE user_id = $1;
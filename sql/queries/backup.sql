@@ -0,0 +1,39 @@
+-- name: BackupUsers :many
+SELECT * FROM users
+ORDER BY created_at;
+
+-- name: BackupUserPasswords :many
+SELECT * FROM user_passwords
+ORDER BY updated_at;
+
+-- name: BackupFeeds :many
+SELECT * FROM feeds
+ORDER BY serial_id;
+
+-- name: BackupFeedFollows :many
+SELECT * FROM feed_follows
+ORDER BY created_at;
+
+-- name: BackupPosts :many
+SELECT * FROM posts
+ORDER BY serial_id;
+
+-- name: BackupPostFeeds :many
+SELECT * FROM post_feeds
+ORDER BY created_at;
+
+-- name: BackupPostReads :many
+SELECT * FROM post_reads
+ORDER BY read_at;
+
+-- name: BackupPostStars :many
+SELECT * FROM post_stars
+ORDER BY starred_at;
+
+-- name: BackupAPITokens :many
+SELECT * FROM api_tokens
+ORDER BY created_at;
+
+-- name: BackupPublishTokens :many
+SELECT * FROM publish_tokens
+ORDER BY created_at;
@@ -9,13 +9,49 @@ SELECT * FROM feeds;
 -- name: GetFeed :one
 SELECT * FROM feeds WHERE feeds.url = $1;
 
+-- name: GetFeedFromID :one
+SELECT * FROM feeds WHERE id = $1;
+
 -- name: MarkFeedFetched :one
-UPDATE feeds SET updated_at = $1, last_fetched_at = $1
-WHERE id = $2
+UPDATE feeds SET updated_at = $1, last_fetched_at = $1, etag = $2, last_modified = $3,
+    consecutive_failures = 0, last_error = NULL, next_fetch_at = NULL
+WHERE id = $4
+RETURNING *;
+
+-- name: ClaimNextFeedToFetch :one
+UPDATE feeds SET last_fetched_at = sqlc.arg(claimed_at)
+WHERE id = (
+    SELECT id FROM feeds
+    WHERE (last_fetched_at IS NULL
+    OR last_fetched_at < sqlc.arg(stale_before))
+    AND (next_fetch_at IS NULL OR next_fetch_at <= sqlc.arg(claimed_at))
+    ORDER BY last_fetched_at ASC NULLS FIRST
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkFeedFailed :one
+UPDATE feeds SET updated_at = $1, consecutive_failures = consecutive_failures + 1,
+    last_error = $2, next_fetch_at = $3
+WHERE id = $4
 RETURNING *;
 
--- name: GetNextFeedToFetch :one
-SELECT * FROM feeds
-ORDER BY feeds.last_fetched_at ASC NULLS FIRST
-LIMIT 1;
+-- name: ResetFeedFetchState :exec
+UPDATE feeds SET last_fetched_at = NULL, etag = NULL, last_modified = NULL,
+    consecutive_failures = 0, last_error = NULL, next_fetch_at = NULL;
 
+-- name: TransferFollowedFeeds :exec
+UPDATE feeds SET user_id = (
+    SELECT feed_follows.user_id FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id
+    AND feed_follows.user_id <> $1
+    ORDER BY feed_follows.created_at
+    LIMIT 1
+)
+WHERE feeds.user_id = $1
+AND EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id
+    AND feed_follows.user_id <> $1
+);
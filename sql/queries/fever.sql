@@ -0,0 +1,84 @@
+-- name: GetUserFromFeverKey :one
+SELECT users.* FROM users
+JOIN api_tokens ON api_tokens.user_id = users.id
+WHERE api_tokens.fever_key = $1;
+
+-- name: GetFeverFeedsForUser :many
+SELECT feeds.id, feeds.serial_id, feeds.name, feeds.url, feeds.last_fetched_at, feed_follows.category FROM feed_follows
+JOIN feeds ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+ORDER BY feeds.serial_id;
+
+-- name: GetFeverItemsForUser :many
+SELECT posts.id, posts.serial_id, posts.title, posts.description, posts.url, posts.published_at, posts.created_at, posts.author,
+    (
+        SELECT min(feeds.serial_id) FROM post_feeds
+        JOIN feeds ON feeds.id = post_feeds.feed_id
+        JOIN feed_follows ON feed_follows.feed_id = post_feeds.feed_id
+        WHERE post_feeds.post_id = posts.id
+        AND feed_follows.user_id = sqlc.arg(user_id)
+    )::bigint AS feed_serial_id,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id
+        AND post_reads.user_id = sqlc.arg(user_id)
+    ) AS is_read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id
+        AND post_stars.user_id = sqlc.arg(user_id)
+    ) AS is_saved
+FROM posts
+WHERE EXISTS (
+    SELECT 1 FROM post_feeds
+    JOIN feed_follows ON feed_follows.feed_id = post_feeds.feed_id
+    WHERE post_feeds.post_id = posts.id
+    AND feed_follows.user_id = sqlc.arg(user_id)
+)
+AND (sqlc.narg(since_id)::bigint IS NULL OR posts.serial_id > sqlc.narg(since_id))
+AND (sqlc.narg(max_id)::bigint IS NULL OR posts.serial_id < sqlc.narg(max_id))
+AND (sqlc.narg(with_ids)::bigint[] IS NULL OR posts.serial_id = ANY(sqlc.narg(with_ids)::bigint[]))
+ORDER BY CASE WHEN sqlc.arg(newest_first)::boolean THEN -posts.serial_id ELSE posts.serial_id END
+LIMIT sqlc.arg(item_limit);
+
+-- name: CountPostsForUser :one
+SELECT count(*) FROM posts
+WHERE EXISTS (
+    SELECT 1 FROM post_feeds
+    JOIN feed_follows ON feed_follows.feed_id = post_feeds.feed_id
+    WHERE post_feeds.post_id = posts.id
+    AND feed_follows.user_id = $1
+);
+
+-- name: GetUnreadPostSerialIDsForUser :many
+SELECT posts.serial_id FROM posts
+WHERE EXISTS (
+    SELECT 1 FROM post_feeds
+    JOIN feed_follows ON feed_follows.feed_id = post_feeds.feed_id
+    WHERE post_feeds.post_id = posts.id
+    AND feed_follows.user_id = $1
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id
+    AND post_reads.user_id = $1
+)
+ORDER BY posts.serial_id;
+
+-- name: GetStarredPostSerialIDsForUser :many
+SELECT posts.serial_id FROM posts
+JOIN post_stars ON post_stars.post_id = posts.id
+WHERE post_stars.user_id = $1
+ORDER BY posts.serial_id;
+
+-- name: GetPostFromSerialID :one
+SELECT * FROM posts WHERE serial_id = $1;
+
+-- name: MarkFeedReadBefore :exec
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT sqlc.arg(user_id)::uuid, post_feeds.post_id, sqlc.arg(read_at)::timestamp
+FROM post_feeds
+JOIN posts ON posts.id = post_feeds.post_id
+WHERE post_feeds.feed_id = sqlc.arg(feed_id)
+AND posts.created_at <= sqlc.arg(created_before)
+ON CONFLICT (user_id, post_id) DO NOTHING;
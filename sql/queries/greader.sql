@@ -0,0 +1,52 @@
+-- name: GetReaderItemsForUser :many
+SELECT posts.id, posts.serial_id, posts.title, posts.description, posts.url, posts.published_at, posts.created_at, posts.author, posts.categories,
+    (
+        SELECT min(feeds.serial_id) FROM post_feeds
+        JOIN feeds ON feeds.id = post_feeds.feed_id
+        JOIN feed_follows ON feed_follows.feed_id = post_feeds.feed_id
+        WHERE post_feeds.post_id = posts.id
+        AND feed_follows.user_id = sqlc.arg(user_id)
+    )::bigint AS feed_serial_id,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id
+        AND post_reads.user_id = sqlc.arg(user_id)
+    ) AS is_read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id
+        AND post_stars.user_id = sqlc.arg(user_id)
+    ) AS is_starred
+FROM posts
+WHERE EXISTS (
+    SELECT 1 FROM post_feeds
+    JOIN feed_follows ON feed_follows.feed_id = post_feeds.feed_id
+    WHERE post_feeds.post_id = posts.id
+    AND feed_follows.user_id = sqlc.arg(user_id)
+    AND (sqlc.narg(feed_id)::uuid IS NULL OR post_feeds.feed_id = sqlc.narg(feed_id))
+    AND (sqlc.narg(category)::text IS NULL OR feed_follows.category = sqlc.narg(category))
+)
+AND (NOT sqlc.arg(starred_only)::boolean OR EXISTS (
+    SELECT 1 FROM post_stars
+    WHERE post_stars.post_id = posts.id
+    AND post_stars.user_id = sqlc.arg(user_id)
+))
+AND (NOT sqlc.arg(read_only)::boolean OR EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id
+    AND post_reads.user_id = sqlc.arg(user_id)
+))
+AND (NOT sqlc.arg(exclude_read)::boolean OR NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id
+    AND post_reads.user_id = sqlc.arg(user_id)
+))
+AND (sqlc.narg(newer_than)::timestamp IS NULL OR coalesce(posts.published_at, posts.created_at) >= sqlc.narg(newer_than))
+AND (sqlc.narg(older_than)::timestamp IS NULL OR coalesce(posts.published_at, posts.created_at) < sqlc.narg(older_than))
+AND (sqlc.narg(with_ids)::bigint[] IS NULL OR posts.serial_id = ANY(sqlc.narg(with_ids)::bigint[]))
+ORDER BY
+    CASE WHEN sqlc.arg(oldest_first)::boolean THEN coalesce(posts.published_at, posts.created_at) END ASC,
+    coalesce(posts.published_at, posts.created_at) DESC,
+    posts.serial_id DESC
+LIMIT sqlc.arg(item_limit)
+OFFSET sqlc.arg(item_offset);
@@ -1,12 +1,113 @@
 -- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, author, categories)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 RETURNING *;
 
+-- name: GetPost :one
+SELECT * FROM posts WHERE id = $1;
+
+-- name: GetPostByURL :one
+SELECT * FROM posts WHERE url = $1
+LIMIT 1;
+
+-- name: GetPostByURLNotInFeed :one
+SELECT * FROM posts
+WHERE url = sqlc.arg(url)
+AND NOT EXISTS (
+    SELECT 1 FROM post_feeds
+    WHERE post_feeds.post_id = posts.id
+    AND post_feeds.feed_id = sqlc.arg(feed_id)
+)
+LIMIT 1;
+
+-- name: LockPostURL :exec
+SELECT pg_advisory_xact_lock(hashtextextended(sqlc.arg(url)::text, 0));
+
+-- name: CreatePostFeed :one
+INSERT INTO post_feeds (post_id, feed_id, guid, created_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: AdoptLegacyPostFeed :one
+UPDATE post_feeds SET guid = sqlc.arg(guid)
+WHERE feed_id = sqlc.arg(feed_id)
+AND guid = 'legacy:' || sqlc.arg(url)::text
+RETURNING *;
+
+-- name: DeleteOrphanedPosts :exec
+DELETE FROM posts
+WHERE NOT EXISTS (
+    SELECT 1 FROM post_feeds
+    WHERE post_feeds.post_id = posts.id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_stars
+    WHERE post_stars.post_id = posts.id
+);
+
+-- name: DeleteUnstarredPosts :exec
+DELETE FROM posts
+WHERE NOT EXISTS (
+    SELECT 1 FROM post_stars
+    WHERE post_stars.post_id = posts.id
+);
+
+-- name: PostFeedExists :one
+SELECT EXISTS (
+    SELECT 1 FROM post_feeds
+    WHERE feed_id = $1
+    AND guid = $2
+);
+
+-- name: PostInUserFeeds :one
+SELECT EXISTS (
+    SELECT 1 FROM post_feeds
+    JOIN feed_follows ON feed_follows.feed_id = post_feeds.feed_id
+    WHERE post_feeds.post_id = $1
+    AND feed_follows.user_id = $2
+);
+
 -- name: GetPostsForUser :many
-SELECT posts.* FROM posts
-JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
-WHERE feed_follows.user_id = $1
+SELECT posts.*, EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id
+    AND post_reads.user_id = sqlc.arg(user_id)
+) AS is_read
+FROM posts
+WHERE EXISTS (
+    SELECT 1 FROM post_feeds
+    JOIN feed_follows ON feed_follows.feed_id = post_feeds.feed_id
+    WHERE post_feeds.post_id = posts.id
+    AND feed_follows.user_id = sqlc.arg(user_id)
+    AND (sqlc.narg(feed_id)::uuid IS NULL OR post_feeds.feed_id = sqlc.narg(feed_id))
+    AND (sqlc.narg(category)::text IS NULL OR feed_follows.category = sqlc.narg(category))
+)
+AND (sqlc.arg(include_read)::boolean OR NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id
+    AND post_reads.user_id = sqlc.arg(user_id)
+))
+AND (sqlc.narg(search)::text IS NULL OR posts.search_vector @@ websearch_to_tsquery('english', sqlc.narg(search)))
 ORDER BY posts.published_at DESC NULLS LAST
-LIMIT $2;
+LIMIT sqlc.arg(post_limit)
+OFFSET sqlc.arg(post_offset);
 
+-- name: SearchPostsForUser :many
+SELECT posts.id, posts.title, posts.url, posts.published_at,
+    ts_rank(posts.search_vector, query)::real AS rank,
+    ts_headline('english', coalesce(posts.description, posts.title, ''), query,
+        'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxFragments=2, MaxWords=20, MinWords=8')::text AS snippet
+FROM posts
+CROSS JOIN websearch_to_tsquery('english', sqlc.arg(query)) AS query
+WHERE posts.search_vector @@ query
+AND EXISTS (
+    SELECT 1 FROM post_feeds
+    JOIN feed_follows ON feed_follows.feed_id = post_feeds.feed_id
+    WHERE post_feeds.post_id = posts.id
+    AND feed_follows.user_id = sqlc.arg(user_id)
+    AND (sqlc.narg(feed_id)::uuid IS NULL OR post_feeds.feed_id = sqlc.narg(feed_id))
+)
+AND (sqlc.narg(since)::timestamp IS NULL OR posts.published_at >= sqlc.narg(since))
+AND (sqlc.narg(until)::timestamp IS NULL OR posts.published_at < sqlc.narg(until))
+ORDER BY rank DESC, posts.published_at DESC NULLS LAST
+LIMIT sqlc.arg(post_limit);
@@ -0,0 +1,10 @@
+-- name: SetPublishToken :exec
+INSERT INTO publish_tokens (user_id, created_at, token_hash)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE
+SET created_at = EXCLUDED.created_at, token_hash = EXCLUDED.token_hash;
+
+-- name: GetUserFromPublishToken :one
+SELECT users.* FROM users
+JOIN publish_tokens ON publish_tokens.user_id = users.id
+WHERE publish_tokens.token_hash = $1;
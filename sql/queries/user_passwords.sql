@@ -0,0 +1,11 @@
+-- name: SetUserPassword :exec
+INSERT INTO user_passwords (user_id, updated_at, password_hash)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE
+SET updated_at = EXCLUDED.updated_at, password_hash = EXCLUDED.password_hash;
+
+-- name: GetUserPasswordHash :one
+SELECT password_hash FROM user_passwords WHERE user_id = $1;
+
+-- name: DeleteUserPassword :exec
+DELETE FROM user_passwords WHERE user_id = $1;
@@ -9,6 +9,9 @@ SELECT * FROM users WHERE name = $1;
 -- name: DeleteAllUsers :exec
 DELETE FROM users;
 
+-- name: DeleteUser :exec
+DELETE FROM users WHERE id = $1;
+
 -- name: GetAllUsers :many
 SELECT name FROM users;
 
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN guid text;
+UPDATE posts SET guid = COALESCE(url, id::text);
+ALTER TABLE posts ALTER COLUMN guid SET NOT NULL;
+ALTER TABLE posts DROP CONSTRAINT posts_url_key;
+ALTER TABLE posts ADD CONSTRAINT uq_posts_feed_id_guid UNIQUE (feed_id, guid);
+
+-- +goose Down
+ALTER TABLE posts DROP CONSTRAINT uq_posts_feed_id_guid;
+ALTER TABLE posts ADD CONSTRAINT posts_url_key UNIQUE (url);
+ALTER TABLE posts DROP COLUMN guid;
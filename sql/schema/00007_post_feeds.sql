@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE post_feeds (
+    post_id uuid NOT NULL,
+    feed_id uuid NOT NULL,
+    guid text NOT NULL,
+    created_at timestamp NOT NULL,
+    CONSTRAINT pk_post_feeds PRIMARY KEY (post_id, feed_id),
+    CONSTRAINT fk_post_feeds_post_id FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+    CONSTRAINT fk_post_feeds_feed_id FOREIGN KEY (feed_id) REFERENCES feeds (id) ON DELETE CASCADE,
+    CONSTRAINT uq_post_feeds_feed_id_guid UNIQUE (feed_id, guid)
+);
+INSERT INTO post_feeds (post_id, feed_id, guid, created_at)
+SELECT id, feed_id, guid, created_at FROM posts;
+ALTER TABLE posts DROP COLUMN guid;
+ALTER TABLE posts DROP COLUMN feed_id;
+
+-- +goose Down
+ALTER TABLE posts ADD COLUMN feed_id uuid;
+ALTER TABLE posts ADD COLUMN guid text;
+UPDATE posts SET feed_id = post_feeds.feed_id, guid = post_feeds.guid
+FROM post_feeds WHERE post_feeds.post_id = posts.id;
+DELETE FROM posts WHERE feed_id IS NULL;
+ALTER TABLE posts ALTER COLUMN feed_id SET NOT NULL;
+ALTER TABLE posts ALTER COLUMN guid SET NOT NULL;
+ALTER TABLE posts ADD CONSTRAINT fk_posts_feed_id FOREIGN KEY (feed_id) REFERENCES feeds (id) ON DELETE CASCADE;
+ALTER TABLE posts ADD CONSTRAINT uq_posts_feed_id_guid UNIQUE (feed_id, guid);
+DROP TABLE post_feeds;
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE post_reads (
+    user_id uuid NOT NULL,
+    post_id uuid NOT NULL,
+    read_at timestamp NOT NULL,
+    CONSTRAINT pk_post_reads PRIMARY KEY (user_id, post_id),
+    CONSTRAINT fk_post_reads_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_post_reads_post_id FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE post_reads;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- Starred posts restrict deletion so that pruning can never remove them
+CREATE TABLE post_stars (
+    user_id uuid NOT NULL,
+    post_id uuid NOT NULL,
+    starred_at timestamp NOT NULL,
+    CONSTRAINT pk_post_stars PRIMARY KEY (user_id, post_id),
+    CONSTRAINT fk_post_stars_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_post_stars_post_id FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE RESTRICT
+);
+
+-- +goose Down
+DROP TABLE post_stars;
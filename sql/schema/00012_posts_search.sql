@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B')
+) STORED;
+CREATE INDEX idx_posts_search_vector ON posts USING GIN (search_vector);
+
+-- +goose Down
+DROP INDEX idx_posts_search_vector;
+ALTER TABLE posts DROP COLUMN search_vector;
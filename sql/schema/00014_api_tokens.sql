@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE api_tokens (
+    id uuid NOT NULL,
+    created_at timestamp NOT NULL,
+    user_id uuid NOT NULL,
+    name text NOT NULL,
+    token_hash text UNIQUE NOT NULL,
+    CONSTRAINT pk_api_tokens PRIMARY KEY (id),
+    CONSTRAINT fk_api_tokens_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE api_tokens;
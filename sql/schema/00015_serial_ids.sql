@@ -0,0 +1,10 @@
+-- +goose Up
+-- Integer ids for sync protocols that cannot address uuids
+ALTER TABLE feeds ADD COLUMN serial_id bigserial UNIQUE NOT NULL;
+ALTER TABLE posts ADD COLUMN serial_id bigserial UNIQUE NOT NULL;
+ALTER TABLE api_tokens ADD COLUMN fever_key text UNIQUE;
+
+-- +goose Down
+ALTER TABLE api_tokens DROP COLUMN fever_key;
+ALTER TABLE posts DROP COLUMN serial_id;
+ALTER TABLE feeds DROP COLUMN serial_id;
@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE publish_tokens (
+    user_id uuid NOT NULL,
+    created_at timestamp NOT NULL,
+    token_hash text UNIQUE NOT NULL,
+    CONSTRAINT pk_publish_tokens PRIMARY KEY (user_id),
+    CONSTRAINT fk_publish_tokens_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE publish_tokens;
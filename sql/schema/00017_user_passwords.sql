@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE user_passwords (
+    user_id uuid NOT NULL,
+    updated_at timestamp NOT NULL,
+    password_hash text NOT NULL,
+    CONSTRAINT pk_user_passwords PRIMARY KEY (user_id),
+    CONSTRAINT fk_user_passwords_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE user_passwords;
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE sessions (
+    id uuid NOT NULL,
+    created_at timestamp NOT NULL,
+    user_id uuid NOT NULL,
+    token_hash text UNIQUE NOT NULL,
+    CONSTRAINT pk_sessions PRIMARY KEY (id),
+    CONSTRAINT fk_sessions_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE sessions;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- Posts stored before feeds' own guids were used got their url as guid,
+-- which no item matches. They are marked so that the next fetch of their
+-- feed can adopt them by url instead of storing them again.
+UPDATE post_feeds SET guid = 'legacy:' || post_feeds.guid
+FROM posts
+WHERE posts.id = post_feeds.post_id
+AND post_feeds.guid = COALESCE(posts.url, posts.id::text);
+
+-- +goose Down
+UPDATE post_feeds SET guid = substr(guid, length('legacy:') + 1)
+WHERE guid LIKE 'legacy:%';
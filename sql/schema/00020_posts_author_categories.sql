@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN author text;
+ALTER TABLE posts ADD COLUMN categories text[];
+
+-- +goose Down
+ALTER TABLE posts DROP COLUMN categories;
+ALTER TABLE posts DROP COLUMN author;
@@ -0,0 +1,23 @@
+-- name: CreateFeedFollows :one
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, category)
+    VALUES (?1, ?2, ?3, ?4, ?5, ?6)
+RETURNING *,
+    (SELECT users.name FROM users WHERE users.id = ?4) AS username,
+    (SELECT feeds.name FROM feeds WHERE feeds.id = ?5) AS feedname;
+
+-- name: GetFeedFollowsForUser :many
+SELECT feeds.name AS feedname, users.name AS username, feeds.url AS feedurl, feed_follows.category, feed_follows.feed_id FROM feed_follows
+    JOIN users ON users.id = feed_follows.user_id
+    JOIN feeds ON feeds.id = feed_follows.feed_id
+WHERE users.name = ?1
+ORDER BY feed_follows.category NULLS FIRST, feeds.name;
+
+-- name: GetFeedFollow :one
+SELECT * FROM feed_follows
+WHERE user_id = ?1
+AND feed_id = ?2;
+
+-- name: DeleteFeedFollow :exec
+DELETE FROM feed_follows
+WHERE user_id = ?1
+AND feed_id = ?2;
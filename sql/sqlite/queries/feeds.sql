@@ -0,0 +1,57 @@
+-- name: CreateFeed :one
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, serial_id)
+    VALUES (?1, ?2, ?3, ?4, ?5, ?6, (SELECT last_value + 1 FROM serial_sequences WHERE name = 'feeds'))
+RETURNING *;
+
+-- name: GetAllFeeds :many
+SELECT * FROM feeds;
+
+-- name: GetFeed :one
+SELECT * FROM feeds WHERE feeds.url = ?1;
+
+-- name: GetFeedFromID :one
+SELECT * FROM feeds WHERE id = ?1;
+
+-- name: MarkFeedFetched :one
+UPDATE feeds SET updated_at = ?1, last_fetched_at = ?1, etag = ?2, last_modified = ?3,
+    consecutive_failures = 0, last_error = NULL, next_fetch_at = NULL
+WHERE id = ?4
+RETURNING *;
+
+-- name: ClaimNextFeedToFetch :one
+-- SQLite serializes writers, so the claim needs no row locks
+UPDATE feeds SET last_fetched_at = ?1
+WHERE id = (
+    SELECT id FROM feeds
+    WHERE (last_fetched_at IS NULL
+    OR last_fetched_at < ?2)
+    AND (next_fetch_at IS NULL OR next_fetch_at <= ?1)
+    ORDER BY last_fetched_at ASC NULLS FIRST
+    LIMIT 1
+)
+RETURNING *;
+
+-- name: MarkFeedFailed :one
+UPDATE feeds SET updated_at = ?1, consecutive_failures = consecutive_failures + 1,
+    last_error = ?2, next_fetch_at = ?3
+WHERE id = ?4
+RETURNING *;
+
+-- name: ResetFeedFetchState :exec
+UPDATE feeds SET last_fetched_at = NULL, etag = NULL, last_modified = NULL,
+    consecutive_failures = 0, last_error = NULL, next_fetch_at = NULL;
+
+-- name: TransferFollowedFeeds :exec
+UPDATE feeds SET user_id = (
+    SELECT feed_follows.user_id FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id
+    AND feed_follows.user_id <> ?1
+    ORDER BY feed_follows.created_at
+    LIMIT 1
+)
+WHERE feeds.user_id = ?1
+AND EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id
+    AND feed_follows.user_id <> ?1
+);
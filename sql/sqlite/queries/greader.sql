@@ -0,0 +1,54 @@
+-- name: GetReaderItemsForUser :many
+SELECT posts.id, posts.serial_id, posts.title, posts.description, posts.url, posts.published_at, posts.created_at, posts.author, posts.categories,
+    (
+        SELECT min(feeds.serial_id) FROM post_feeds
+        JOIN feeds ON feeds.id = post_feeds.feed_id
+        JOIN feed_follows ON feed_follows.feed_id = post_feeds.feed_id
+        WHERE post_feeds.post_id = posts.id
+        AND feed_follows.user_id = ?1
+    ) AS feed_serial_id,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id
+        AND post_reads.user_id = ?1
+    ) AS is_read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id
+        AND post_stars.user_id = ?1
+    ) AS is_starred
+FROM posts
+WHERE EXISTS (
+    SELECT 1 FROM post_feeds
+    JOIN feed_follows ON feed_follows.feed_id = post_feeds.feed_id
+    WHERE post_feeds.post_id = posts.id
+    AND feed_follows.user_id = ?1
+    AND (?2 IS NULL OR post_feeds.feed_id = ?2)
+    AND (?3 IS NULL OR feed_follows.category = ?3)
+)
+AND (NOT ?4 OR EXISTS (
+    SELECT 1 FROM post_stars
+    WHERE post_stars.post_id = posts.id
+    AND post_stars.user_id = ?1
+))
+AND (NOT ?5 OR EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id
+    AND post_reads.user_id = ?1
+))
+AND (NOT ?6 OR NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id
+    AND post_reads.user_id = ?1
+))
+AND (?7 IS NULL OR coalesce(posts.published_at, posts.created_at) >= ?7)
+AND (?8 IS NULL OR coalesce(posts.published_at, posts.created_at) < ?8)
+AND (?9 IS NULL OR posts.serial_id IN (
+    SELECT value FROM json_each('[' || substr(?9, 2, length(?9) - 2) || ']')
+))
+ORDER BY
+    CASE WHEN ?10 THEN coalesce(posts.published_at, posts.created_at) END ASC,
+    coalesce(posts.published_at, posts.created_at) DESC,
+    posts.serial_id DESC
+LIMIT ?11
+OFFSET ?12;
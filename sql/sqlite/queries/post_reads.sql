@@ -0,0 +1,24 @@
+-- name: MarkPostRead :exec
+INSERT INTO post_reads (user_id, post_id, read_at)
+VALUES (?1, ?2, ?3)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: MarkPostUnread :exec
+DELETE FROM post_reads
+WHERE user_id = ?1
+AND post_id = ?2;
+
+-- name: MarkFeedRead :exec
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT ?1, post_feeds.post_id, ?2
+FROM post_feeds
+WHERE post_feeds.feed_id = ?3
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: MarkFeedUnread :exec
+DELETE FROM post_reads
+WHERE post_reads.user_id = ?1
+AND post_reads.post_id IN (
+    SELECT post_feeds.post_id FROM post_feeds
+    WHERE post_feeds.feed_id = ?2
+);
@@ -0,0 +1,17 @@
+-- name: StarPost :exec
+INSERT INTO post_stars (user_id, post_id, starred_at)
+VALUES (?1, ?2, ?3)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: UnstarPost :exec
+DELETE FROM post_stars
+WHERE user_id = ?1
+AND post_id = ?2;
+
+-- name: GetStarredPostsForUser :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.description, posts.url, posts.published_at,
+    NULL AS search_vector, posts.serial_id, posts.author, posts.categories, post_stars.starred_at FROM posts
+JOIN post_stars ON post_stars.post_id = posts.id
+WHERE post_stars.user_id = ?1
+ORDER BY post_stars.starred_at DESC
+LIMIT ?2;
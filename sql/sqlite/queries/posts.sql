@@ -0,0 +1,122 @@
+-- Posts have no search_vector column in SQLite. Queries return NULL in its
+-- place so that rows match the Postgres ones.
+
+-- name: CreatePost :one
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, author, categories, serial_id)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, (SELECT last_value + 1 FROM serial_sequences WHERE name = 'posts'))
+RETURNING id, created_at, updated_at, title, description, url, published_at, NULL AS search_vector, serial_id, author, categories;
+
+-- name: GetPost :one
+SELECT id, created_at, updated_at, title, description, url, published_at, NULL AS search_vector, serial_id, author, categories FROM posts
+WHERE id = ?1;
+
+-- name: GetPostByURL :one
+SELECT id, created_at, updated_at, title, description, url, published_at, NULL AS search_vector, serial_id, author, categories FROM posts
+WHERE url = ?1
+LIMIT 1;
+
+-- name: GetPostByURLNotInFeed :one
+SELECT id, created_at, updated_at, title, description, url, published_at, NULL AS search_vector, serial_id, author, categories FROM posts
+WHERE url = ?1
+AND NOT EXISTS (
+    SELECT 1 FROM post_feeds
+    WHERE post_feeds.post_id = posts.id
+    AND post_feeds.feed_id = ?2
+)
+LIMIT 1;
+
+-- name: LockPostURL :exec
+-- SQLite runs one transaction at a time, so there is no other writer to
+-- lock out.
+SELECT ?1;
+
+-- name: CreatePostFeed :one
+INSERT INTO post_feeds (post_id, feed_id, guid, created_at)
+VALUES (?1, ?2, ?3, ?4)
+RETURNING *;
+
+-- name: AdoptLegacyPostFeed :one
+UPDATE post_feeds SET guid = ?1
+WHERE feed_id = ?2
+AND guid = 'legacy:' || ?3
+RETURNING *;
+
+-- name: DeleteOrphanedPosts :exec
+DELETE FROM posts
+WHERE NOT EXISTS (
+    SELECT 1 FROM post_feeds
+    WHERE post_feeds.post_id = posts.id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_stars
+    WHERE post_stars.post_id = posts.id
+);
+
+-- name: DeleteUnstarredPosts :exec
+DELETE FROM posts
+WHERE NOT EXISTS (
+    SELECT 1 FROM post_stars
+    WHERE post_stars.post_id = posts.id
+);
+
+-- name: PostFeedExists :one
+SELECT EXISTS (
+    SELECT 1 FROM post_feeds
+    WHERE feed_id = ?1
+    AND guid = ?2
+);
+
+-- name: PostInUserFeeds :one
+SELECT EXISTS (
+    SELECT 1 FROM post_feeds
+    JOIN feed_follows ON feed_follows.feed_id = post_feeds.feed_id
+    WHERE post_feeds.post_id = ?1
+    AND feed_follows.user_id = ?2
+);
+
+-- name: GetPostsForUser :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.description, posts.url, posts.published_at,
+    NULL AS search_vector, posts.serial_id, posts.author, posts.categories, EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id
+    AND post_reads.user_id = ?1
+) AS is_read
+FROM posts
+WHERE EXISTS (
+    SELECT 1 FROM post_feeds
+    JOIN feed_follows ON feed_follows.feed_id = post_feeds.feed_id
+    WHERE post_feeds.post_id = posts.id
+    AND feed_follows.user_id = ?1
+    AND (?2 IS NULL OR post_feeds.feed_id = ?2)
+    AND (?3 IS NULL OR feed_follows.category = ?3)
+)
+AND (?4 OR NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id
+    AND post_reads.user_id = ?1
+))
+AND (?5 IS NULL OR posts.serial_id IN (
+    SELECT rowid FROM posts_search WHERE posts_search MATCH websearch_to_fts5(?5)
+))
+ORDER BY posts.published_at DESC NULLS LAST
+LIMIT ?6
+OFFSET ?7;
+
+-- name: SearchPostsForUser :many
+SELECT posts.id, posts.title, posts.url, posts.published_at,
+    -bm25(posts_search, 2.0, 1.0) AS rank,
+    snippet(posts_search, -1, char(2), char(3), '…', 20) AS snippet
+FROM posts_search
+JOIN posts ON posts.serial_id = posts_search.rowid
+WHERE posts_search MATCH websearch_to_fts5(?1)
+AND EXISTS (
+    SELECT 1 FROM post_feeds
+    JOIN feed_follows ON feed_follows.feed_id = post_feeds.feed_id
+    WHERE post_feeds.post_id = posts.id
+    AND feed_follows.user_id = ?2
+    AND (?3 IS NULL OR post_feeds.feed_id = ?3)
+)
+AND (?4 IS NULL OR posts.published_at >= ?4)
+AND (?5 IS NULL OR posts.published_at < ?5)
+ORDER BY rank DESC, posts.published_at DESC NULLS LAST
+LIMIT ?6;
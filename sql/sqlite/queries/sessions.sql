@@ -0,0 +1,15 @@
+-- name: CreateSession :one
+INSERT INTO sessions (id, created_at, user_id, token_hash)
+VALUES (?1, ?2, ?3, ?4)
+RETURNING *;
+
+-- name: GetUserFromSession :one
+SELECT users.* FROM users
+JOIN sessions ON sessions.user_id = users.id
+WHERE sessions.token_hash = ?1;
+
+-- name: DeleteSession :exec
+DELETE FROM sessions WHERE token_hash = ?1;
+
+-- name: DeleteSessionsForUser :exec
+DELETE FROM sessions WHERE user_id = ?1;
@@ -0,0 +1,19 @@
+-- name: CreateUser :one
+INSERT INTO users (id, created_at, updated_at, name)
+    VALUES (?1, ?2, ?3, ?4)
+RETURNING *;
+
+-- name: GetUser :one
+SELECT * FROM users WHERE name = ?1;
+
+-- name: DeleteAllUsers :exec
+DELETE FROM users;
+
+-- name: DeleteUser :exec
+DELETE FROM users WHERE id = ?1;
+
+-- name: GetAllUsers :many
+SELECT name FROM users;
+
+-- name: GetUserFromID :one
+SELECT * FROM users WHERE id = ?1;
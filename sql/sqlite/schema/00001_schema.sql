@@ -0,0 +1,146 @@
+-- +goose Up
+-- The SQLite schema matches the Postgres schema column for column, so that
+-- the generated code can scan rows from either backend.
+CREATE TABLE users (
+    id text NOT NULL,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    name text UNIQUE NOT NULL,
+    CONSTRAINT pk_user PRIMARY KEY (id)
+);
+
+CREATE TABLE feeds (
+    id text NOT NULL,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    name text NOT NULL,
+    url text UNIQUE NOT NULL,
+    user_id text NOT NULL,
+    last_fetched_at timestamp,
+    etag text,
+    last_modified text,
+    consecutive_failures integer NOT NULL DEFAULT 0,
+    last_error text,
+    next_fetch_at timestamp,
+    serial_id integer UNIQUE NOT NULL,
+    CONSTRAINT pk_feed PRIMARY KEY (id),
+    CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE feed_follows (
+    id text UNIQUE NOT NULL,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    user_id text NOT NULL,
+    feed_id text NOT NULL,
+    category text,
+    CONSTRAINT fk_feed_follows_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_feed_follows_feed_id FOREIGN KEY (feed_id) REFERENCES feeds (id) ON DELETE CASCADE,
+    CONSTRAINT uq_user_id_feed_id UNIQUE (user_id, feed_id)
+);
+
+-- There is no tsvector column; posts_search below indexes posts instead
+CREATE TABLE posts (
+    id text NOT NULL,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    title text,
+    description text,
+    url text,
+    published_at timestamp,
+    serial_id integer UNIQUE NOT NULL,
+    CONSTRAINT pk_posts PRIMARY KEY (id),
+    CONSTRAINT ck_posts_title_description_or_null CHECK (description IS NOT NULL OR title IS NOT NULL)
+);
+CREATE INDEX idx_posts_url ON posts (url);
+
+CREATE TABLE post_feeds (
+    post_id text NOT NULL,
+    feed_id text NOT NULL,
+    guid text NOT NULL,
+    created_at timestamp NOT NULL,
+    CONSTRAINT pk_post_feeds PRIMARY KEY (post_id, feed_id),
+    CONSTRAINT fk_post_feeds_post_id FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+    CONSTRAINT fk_post_feeds_feed_id FOREIGN KEY (feed_id) REFERENCES feeds (id) ON DELETE CASCADE,
+    CONSTRAINT uq_post_feeds_feed_id_guid UNIQUE (feed_id, guid)
+);
+
+CREATE TABLE post_reads (
+    user_id text NOT NULL,
+    post_id text NOT NULL,
+    read_at timestamp NOT NULL,
+    CONSTRAINT pk_post_reads PRIMARY KEY (user_id, post_id),
+    CONSTRAINT fk_post_reads_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_post_reads_post_id FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+);
+
+-- Starred posts restrict deletion so that pruning can never remove them
+CREATE TABLE post_stars (
+    user_id text NOT NULL,
+    post_id text NOT NULL,
+    starred_at timestamp NOT NULL,
+    CONSTRAINT pk_post_stars PRIMARY KEY (user_id, post_id),
+    CONSTRAINT fk_post_stars_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_post_stars_post_id FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE RESTRICT
+);
+
+CREATE TABLE api_tokens (
+    id text NOT NULL,
+    created_at timestamp NOT NULL,
+    user_id text NOT NULL,
+    name text NOT NULL,
+    token_hash text UNIQUE NOT NULL,
+    fever_key text UNIQUE,
+    CONSTRAINT pk_api_tokens PRIMARY KEY (id),
+    CONSTRAINT fk_api_tokens_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE publish_tokens (
+    user_id text NOT NULL,
+    created_at timestamp NOT NULL,
+    token_hash text UNIQUE NOT NULL,
+    CONSTRAINT pk_publish_tokens PRIMARY KEY (user_id),
+    CONSTRAINT fk_publish_tokens_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE VIRTUAL TABLE posts_search USING fts5 (
+    title,
+    description,
+    content = 'posts',
+    content_rowid = 'serial_id'
+);
+
+-- +goose StatementBegin
+CREATE TRIGGER trg_posts_search_insert AFTER INSERT ON posts BEGIN
+    INSERT INTO posts_search (rowid, title, description)
+    VALUES (new.serial_id, new.title, new.description);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER trg_posts_search_delete AFTER DELETE ON posts BEGIN
+    INSERT INTO posts_search (posts_search, rowid, title, description)
+    VALUES ('delete', old.serial_id, old.title, old.description);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER trg_posts_search_update AFTER UPDATE ON posts BEGIN
+    INSERT INTO posts_search (posts_search, rowid, title, description)
+    VALUES ('delete', old.serial_id, old.title, old.description);
+    INSERT INTO posts_search (rowid, title, description)
+    VALUES (new.serial_id, new.title, new.description);
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE posts_search;
+DROP TABLE publish_tokens;
+DROP TABLE api_tokens;
+DROP TABLE post_stars;
+DROP TABLE post_reads;
+DROP TABLE post_feeds;
+DROP TABLE posts;
+DROP TABLE feed_follows;
+DROP TABLE feeds;
+DROP TABLE users;
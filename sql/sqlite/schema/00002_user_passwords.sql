@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE user_passwords (
+    user_id text NOT NULL,
+    updated_at timestamp NOT NULL,
+    password_hash text NOT NULL,
+    CONSTRAINT pk_user_passwords PRIMARY KEY (user_id),
+    CONSTRAINT fk_user_passwords_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE sessions (
+    id text NOT NULL,
+    created_at timestamp NOT NULL,
+    user_id text NOT NULL,
+    token_hash text UNIQUE NOT NULL,
+    CONSTRAINT pk_sessions PRIMARY KEY (id),
+    CONSTRAINT fk_sessions_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE sessions;
+DROP TABLE user_passwords;
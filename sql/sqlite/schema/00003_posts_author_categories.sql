@@ -0,0 +1,9 @@
+-- +goose Up
+-- SQLite has no arrays; categories hold the Postgres array literal the
+-- generated queries send and scan.
+ALTER TABLE posts ADD COLUMN author text;
+ALTER TABLE posts ADD COLUMN categories text;
+
+-- +goose Down
+ALTER TABLE posts DROP COLUMN categories;
+ALTER TABLE posts DROP COLUMN author;
@@ -0,0 +1,31 @@
+-- +goose Up
+-- Serial ids come from here rather than max(serial_id), so that like the
+-- Postgres bigserial columns they never go back to a deleted row's id.
+CREATE TABLE serial_sequences (
+    name text NOT NULL,
+    last_value integer NOT NULL,
+    CONSTRAINT pk_serial_sequences PRIMARY KEY (name)
+);
+INSERT INTO serial_sequences (name, last_value)
+SELECT 'feeds', coalesce(max(serial_id), 0) FROM feeds;
+INSERT INTO serial_sequences (name, last_value)
+SELECT 'posts', coalesce(max(serial_id), 0) FROM posts;
+
+-- +goose StatementBegin
+CREATE TRIGGER trg_feeds_serial_id AFTER INSERT ON feeds BEGIN
+    UPDATE serial_sequences SET last_value = max(last_value, new.serial_id)
+    WHERE name = 'feeds';
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER trg_posts_serial_id AFTER INSERT ON posts BEGIN
+    UPDATE serial_sequences SET last_value = max(last_value, new.serial_id)
+    WHERE name = 'posts';
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER trg_posts_serial_id;
+DROP TRIGGER trg_feeds_serial_id;
+DROP TABLE serial_sequences;